@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pbergman/provider"
 )
@@ -46,15 +48,12 @@ const (
 type ApiClient interface {
 	provider.Client
 	provider.ZoneAwareClient
-}
-
-type ErrorResponse struct {
-	Message string `json:"error"`
-	Code    int    `json:"-"`
-}
-
-func (e ErrorResponse) Error() string {
-	return e.Message
+	DomainIterator
+	RateLimitAware
+	DNSSecClient
+	NameserverClient
+	TokenRenewer
+	PermissionChecker
 }
 
 type Links []*Link
@@ -64,20 +63,45 @@ type Link struct {
 	Link string `json:"link"`
 }
 
+// Get returns the link with given relation or nil when not found
+func (l Links) Get(rel string) *Link {
+	for _, link := range l {
+		if nil != link && link.Rel == rel {
+			return link
+		}
+	}
+	return nil
+}
+
 func NewClient(config Config, storage Storage, mode ControleMode) ApiClient {
 
 	var object = &client{
+		config:  config,
 		control: mode,
 		buf:     NewBufPool(),
+		limiter: &rateLimiter{threshold: DefaultRateLimitThreshold},
+		retry:   newRetryPolicy(config),
+	}
+
+	if v, o := config.(ConfigRateLimit); o && v.GetRateLimitThreshold() != 0 {
+		object.limiter.threshold = v.GetRateLimitThreshold()
 	}
 
-	var transporter http.RoundTripper = &transport{
+	object.tokens = &transport{
 		RoundTripper: http.DefaultTransport,
 		refresh:      object.Authorize,
 		config:       config,
 		storage:      storage,
+		limiter:      object.limiter,
+		leeway:       DefaultTokenRenewalLeeway,
 	}
 
+	if v, o := config.(ConfigTokenRenewal); o && v.GetTokenRenewalLeeway() != 0 {
+		object.tokens.leeway = v.GetTokenRenewalLeeway()
+	}
+
+	var transporter http.RoundTripper = object.tokens
+
 	if v, ok := config.(provider.DebugConfig); ok {
 		transporter = &provider.DebugTransport{
 			RoundTripper: transporter,
@@ -104,7 +128,17 @@ func ContextValue[T any](ctx context.Context, name string, defaultOnNil T) T {
 type client struct {
 	client  *http.Client
 	buf     *sync.Pool
+	config  Config
 	control ControleMode
+	limiter *rateLimiter
+	tokens  *transport
+	retry   *retryPolicy
+	// key is the index of the last accepted key (see ConfigKeyRotation)
+	key atomic.Int32
+}
+
+func (a *client) RateLimit() RateLimit {
+	return a.limiter.State()
 }
 
 func (a *client) toDnsPath(domain string) string {
@@ -113,6 +147,63 @@ func (a *client) toDnsPath(domain string) string {
 
 func (a *client) fetch(ctx context.Context, path string, method string, body io.Reader, object any) error {
 
+	if plan := DryRun(ctx); nil != plan && method != http.MethodGet {
+		return plan.record(method, path, body)
+	}
+
+	// the transport closes (and for retries rereads) the body, possibly
+	// after Do returns, so it gets a copy that can be replayed instead of
+	// a buffer that will be returned to the pool by the caller
+	var data []byte
+
+	if nil != body {
+		x, err := io.ReadAll(body)
+
+		if err != nil {
+			return err
+		}
+
+		data = x
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := a.do(ctx, path, method, data, object)
+
+		if err == nil {
+			return nil
+		}
+
+		wait, ok := a.retry.next(ctx, method, attempt, err)
+
+		if false == ok || nil != sleep(ctx, wait) {
+			return withAttempts(err, attempt)
+		}
+	}
+}
+
+// withAttempts adds the number of attempts to the error when the request
+// was retried.
+func withAttempts(err error, attempts int) error {
+
+	if attempts <= 1 {
+		return err
+	}
+
+	if x, ok := err.(ErrorResponse); ok {
+		x.Attempts = attempts
+		return x
+	}
+
+	return fmt.Errorf("%w (after %d attempts)", err, attempts)
+}
+
+func (a *client) do(ctx context.Context, path string, method string, data []byte, object any) error {
+	var body io.Reader
+
+	if nil != data {
+		body = bytes.NewReader(data)
+	}
+
 	request, err := http.NewRequestWithContext(ctx, method, path, body)
 
 	if err != nil {
@@ -127,29 +218,43 @@ func (a *client) fetch(ctx context.Context, path string, method string, body io.
 
 	defer response.Body.Close()
 
-	if !strings.HasPrefix(response.Header.Get("content-type"), "application/json") {
-		return fmt.Errorf("unexpected response type: %s", response.Header.Get("content-type"))
-	}
+	return decodeResponse(response, object)
+}
+
+// decodeResponse decodes the body of a successful response in the object
+// or returns an ErrorResponse for all other responses.
+func decodeResponse(response *http.Response, object any) error {
+	var isJson = strings.HasPrefix(response.Header.Get("content-type"), "application/json")
 
 	if response.StatusCode >= http.StatusOK && response.StatusCode < http.StatusMultipleChoices {
+
+		if false == isJson {
+			return fmt.Errorf("unexpected response type: %s", response.Header.Get("content-type"))
+		}
+
 		if nil != object {
 			if err := json.NewDecoder(response.Body).Decode(object); err != nil {
 				return err
 			}
 		}
-	} else {
-		var message ErrorResponse
 
-		if err := json.NewDecoder(response.Body).Decode(&message); err != nil {
-			return err
-		}
+		return nil
+	}
 
-		message.Code = response.StatusCode
+	var message = ErrorResponse{
+		Code:   response.StatusCode,
+		Method: response.Request.Method,
+		Path:   response.Request.URL.Path,
+	}
+
+	message.retryAfter, _ = parseRetryAfter(response.Header.Get("retry-after"))
 
-		return message
+	// errors from proxies or load balancers are not always json
+	if false == isJson || nil != json.NewDecoder(response.Body).Decode(&message) || "" == message.Message {
+		message.Message = http.StatusText(response.StatusCode)
 	}
 
-	return nil
+	return message
 }
 
 var (
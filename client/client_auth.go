@@ -9,8 +9,11 @@ import (
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
 type AuthRequest struct {
@@ -24,7 +27,57 @@ type AuthRequest struct {
 
 func (c *client) Authorize(ctx context.Context, config Config) (string, error) {
 
-	request, err := c.makeAuthorizeRequest(ctx, config)
+	if v, o := config.(ConfigKeyRotation); o && v.KeyCount() > 0 {
+		return c.authorizeRotation(ctx, config, v)
+	}
+
+	signer, err := getSigner(config)
+
+	if err != nil {
+		return "", err
+	}
+
+	return c.authorize(ctx, config, signer)
+}
+
+// authorizeRotation tries the candidate keys, starting with the key that
+// was accepted last, until one is accepted.
+func (c *client) authorizeRotation(ctx context.Context, config Config, keys ConfigKeyRotation) (string, error) {
+	var count = keys.KeyCount()
+	var start = int(c.key.Load()) % count
+	var errs = make([]error, 0)
+
+	for i := 0; i < count; i++ {
+		var index = (start + i) % count
+
+		signer, err := keys.GetKey(index)
+
+		if err == nil {
+			var token string
+
+			if token, err = c.authorize(ctx, config, signer); err == nil {
+				c.key.Store(int32(index))
+				keys.KeyAccepted(index)
+				return token, nil
+			}
+
+			// only errors that tell the key is not (or no longer) valid
+			if false == errors.Is(err, ErrBadSignature) && false == errors.Is(err, ErrKeyExpired) {
+				return "", err
+			}
+		}
+
+		keys.KeyRejected(index, err)
+
+		errs = append(errs, fmt.Errorf("key %d: %w", index, err))
+	}
+
+	return "", fmt.Errorf("none of the %d keys was accepted: %w", count, errors.Join(errs...))
+}
+
+func (c *client) authorize(ctx context.Context, config Config, signer crypto.Signer) (string, error) {
+
+	request, err := c.makeAuthorizeRequest(ctx, config, signer)
 
 	if err != nil {
 		return "", err
@@ -42,34 +95,60 @@ func (c *client) Authorize(ctx context.Context, config Config) (string, error) {
 		Token string `json:"token"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", err
+	if err := decodeResponse(resp, &data); err != nil {
+		return "", withClockSkew(resp, err)
+	}
+
+	if "" == data.Token {
+		return "", fmt.Errorf("authentication response holds no token: %w", ErrUnauthorized)
 	}
 
 	return data.Token, nil
 }
 
-func (c *client) makeAuthorizeRequest(ctx context.Context, config Config) (*http.Request, error) {
+// maxClockSkew is the difference with the clock of the api at which a
+// failed authentication is reported as ErrClockSkew
+const maxClockSkew = 5 * time.Minute
+
+// withClockSkew adds the clock difference to an unauthorized response
+// (without a more specific reason) when the local clock is off, which
+// will make it unwrap to ErrClockSkew.
+func withClockSkew(response *http.Response, err error) error {
+	x, ok := err.(ErrorResponse)
+
+	if false == ok || x.Code != http.StatusUnauthorized || x.unwrapAuth() != ErrUnauthorized {
+		return err
+	}
+
+	date, e := http.ParseTime(response.Header.Get("date"))
+
+	if e != nil {
+		return err
+	}
+
+	if skew := time.Since(date).Round(time.Second); skew.Abs() > maxClockSkew {
+		x.Message += fmt.Sprintf(" (local clock differs %s from the server clock)", skew)
+	}
+
+	return x
+}
+
+func (c *client) makeAuthorizeRequest(ctx context.Context, config Config, signer crypto.Signer) (*http.Request, error) {
 
 	var payload = NewAuthRequest(config)
 	var hasher = sha512.New()
 	var body = new(bytes.Buffer)
 	var writer = io.MultiWriter(hasher, body)
 
-	key, err := config.GetPrivateKey()
-
-	if err != nil {
-		return nil, err
-	}
-
 	if err := json.NewEncoder(writer).Encode(payload); err != nil {
 		return nil, err
 	}
 
-	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA512, hasher.Sum(nil))
+	// for rsa keys this is a PKCS #1 v1.5 signature of the sha512 digest
+	signature, err := signer.Sign(rand.Reader, hasher.Sum(nil), crypto.SHA512)
 
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to sign authentication request: %w", err)
 	}
 
 	request, err := http.NewRequestWithContext(context.WithValue(ctx, "authorize", false), http.MethodPost, "auth", body)
@@ -83,6 +162,36 @@ func (c *client) makeAuthorizeRequest(ctx context.Context, config Config) (*http
 	return request, nil
 }
 
+// getSigner returns the signer of the config (see ConfigSigner) or falls
+// back to the private key.
+func getSigner(config Config) (crypto.Signer, error) {
+
+	if v, o := config.(ConfigSigner); o {
+		signer, err := v.GetSigner()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if nil != signer {
+
+			if _, ok := signer.Public().(*rsa.PublicKey); false == ok {
+				return nil, fmt.Errorf("unsupported signer public key %T, TransIP only supports rsa keys", signer.Public())
+			}
+
+			return signer, nil
+		}
+	}
+
+	key, err := config.GetPrivateKey()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
 func NewAuthRequest(config Config) *AuthRequest {
 
 	var payload = &AuthRequest{
@@ -93,19 +202,25 @@ func NewAuthRequest(config Config) *AuthRequest {
 
 	if v, o := config.(ConfigLabel); o {
 		payload.Label = v.Label()
-	} else {
-		payload.Label = "libdns client - " + random(4)
+	}
+
+	if "" == payload.Label {
+		payload.Label = FormatLabel(DefaultLabelTemplate, "")
 	}
 
 	if v, o := config.(ConfigExpirationTime); o {
 		payload.ExpirationTime = v.ExpirationTime()
-	} else {
+	}
+
+	if "" == payload.ExpirationTime {
 		payload.ExpirationTime = ExpirationTime1Hour
 	}
 
 	if v, o := config.(ConfigNonce); o {
 		payload.Nonce = v.Nonce()
-	} else {
+	}
+
+	if "" == payload.Nonce {
 		payload.Nonce = random(8)
 	}
 
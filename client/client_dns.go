@@ -2,14 +2,23 @@ package client
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/libdns/libdns"
 	"github.com/pbergman/provider"
 )
 
+// RecordTypes holds the record types that can be stored at TransIP
+var RecordTypes = []string{"A", "AAAA", "CNAME", "MX", "NS", "TXT", "SRV", "SSHFP", "TLSA", "CAA", "NAPTR", "ALIAS", "DS"}
+
+// IsSupportedType returns true when records of given type can be stored
+func IsSupportedType(x string) bool {
+	return slices.Contains(RecordTypes, x)
+}
+
 type DNSEntries struct {
 	Entries []*DNSRecord `json:"dnsEntries"`
 }
@@ -46,44 +55,95 @@ func MarshallDNSRecords(data *libdns.RR, zone string) *DNSRecord {
 	return record
 }
 
+// ParseDNSRecord returns the specific type (libdns.Address, libdns.MX, TLSA,
+// SSHFP etc.) for the entry. Invalid content will return an error that wraps
+// ErrInvalidRecord.
+func ParseDNSRecord(data *DNSRecord, zone string) (libdns.Record, error) {
+	record, err := parseRecord(*MarshallRRRecord(data, zone))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse %s record \"%s\": %w", ErrInvalidRecord, data.Type, data.Name, err)
+	}
+
+	return record, nil
+}
+
+// RawRecord is an entry exactly as stored at TransIP. It is returned for
+// entries that can't be parsed or of which the content would be changed by
+// the typed record (an unquoted CAA value, an uppercase AAAA etc.), so
+// deletes and patches will send the content that is stored. It has no Parse
+// method, so the provider package won't parse (and reject) it again.
+type RawRecord libdns.RR
+
+func (r RawRecord) RR() libdns.RR {
+	return libdns.RR(r)
+}
+
+// readDNSRecord is the lenient counterpart of ParseDNSRecord for entries
+// that are already stored, which falls back to a RawRecord.
+func readDNSRecord(data *DNSRecord, zone string) libdns.Record {
+	var rr = *MarshallRRRecord(data, zone)
+
+	if record, err := parseRecord(rr); err == nil && record.RR() == rr {
+		return record
+	}
+
+	return RawRecord(rr)
+}
+
+// MarshallDNSRecord returns the entry for any libdns record, it is the
+// counterpart of ParseDNSRecord.
+func MarshallDNSRecord(record libdns.Record, zone string) *DNSRecord {
+	var rr = record.RR()
+	return MarshallDNSRecords(&rr, zone)
+}
+
 func (c *client) SetDNSList(ctx context.Context, domain string, change provider.ChangeList) ([]libdns.Record, error) {
 
 	if false == change.Has(provider.Delete|provider.Create) {
 		return nil, nil
 	}
 
-	switch c.control {
-	case FullZoneControl:
-
-		var data = &DNSEntries{
-			Entries: make([]*DNSRecord, 0),
-		}
-
-		for record := range change.Iterate(provider.NoChange | provider.Create) {
-			data.Entries = append(data.Entries, MarshallDNSRecords(record, domain))
-		}
+	if err := c.guardWrite(ctx); err != nil {
+		return nil, err
+	}
 
-		var buffer = c.buf.Get().(*buf)
+	change, err := c.applyTTLPolicy(domain, change)
 
-		defer buffer.Close()
+	if err != nil {
+		return nil, err
+	}
 
-		if err := json.NewEncoder(buffer).Encode(data); err != nil {
+	// validate locally so malformed content won't end up in a partial update
+	for record := range change.Iterate(provider.Create) {
+		if _, err := ParseDNSRecord(MarshallDNSRecords(record, domain), domain); err != nil {
 			return nil, err
 		}
+	}
 
-		if err := c.fetch(ctx, c.toDnsPath(domain), http.MethodPut, buffer, nil); err != nil {
+	switch c.control {
+	case FullZoneControl:
+
+		if err := c.replaceZone(ctx, domain, change); err != nil {
 			return nil, err
 		}
 
 	default:
 
-		if err := c.mutate(ctx, domain, change, provider.Delete); err != nil {
+		if err := c.updateRecords(ctx, domain, change); err != nil {
 			return nil, err
 		}
+	}
 
-		if err := c.mutate(ctx, domain, change, provider.Create); err != nil {
-			return nil, err
+	// nothing was changed so return the records as they would be stored
+	if nil != DryRun(ctx) {
+		var records = make([]libdns.Record, 0)
+
+		for record := range change.Iterate(provider.NoChange | provider.Create) {
+			records = append(records, readDNSRecord(MarshallDNSRecords(record, domain), domain))
 		}
+
+		return records, nil
 	}
 
 	return nil, nil
@@ -99,7 +159,7 @@ func (c *client) GetDNSList(ctx context.Context, domain string) ([]libdns.Record
 	var records = make([]libdns.Record, len(data.Entries))
 
 	for i, c := 0, len(data.Entries); i < c; i++ {
-		records[i] = MarshallRRRecord(data.Entries[i], domain)
+		records[i] = readDNSRecord(data.Entries[i], domain)
 	}
 
 	return records, nil
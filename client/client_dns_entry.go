@@ -3,7 +3,9 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/pbergman/provider"
 )
@@ -12,33 +14,80 @@ type DNSEntry struct {
 	Entry *DNSRecord `json:"dnsEntry"`
 }
 
-func (c *client) mutate(ctx context.Context, domain string, change provider.ChangeList, state provider.ChangeState) error {
-	var method string
+// updateRecords will apply the change record by record. Records that only
+// change content are updated in place with a PATCH, so there is no window
+// where the name has no record. Others are deleted and (re)created.
+//
+// The api identifies the entry to patch by name, type and expire so a patch
+// is only possible when the expire is unchanged and the zone has no other
+// entry with the same name, type and expire.
+func (c *client) updateRecords(ctx context.Context, domain string, change provider.ChangeList) error {
+	var zone = marshallChanges(change, provider.NoChange|provider.Delete, domain)
+	var deletes = marshallChanges(change, provider.Delete, domain)
+	var creates = marshallChanges(change, provider.Create, domain)
+	var key = func(x *DNSRecord) string {
+		return fmt.Sprintf("%s\x00%s\x00%d", strings.ToLower(x.Name), x.Type, x.Expire)
+	}
+
+	var count = make(map[string]int)
 
-	switch state {
-	case provider.Delete:
-		method = http.MethodDelete
-	case provider.Create:
-		method = http.MethodPost
-	default:
-		method = http.MethodPatch
+	for _, entry := range zone {
+		count[key(entry)]++
 	}
 
-	var buf = c.buf.Get().(*buf)
+	for i := 0; i < len(deletes); i++ {
 
-	defer buf.Close()
+		if count[key(deletes[i])] != 1 {
+			continue
+		}
+
+		for j := 0; j < len(creates); j++ {
+			if key(deletes[i]) == key(creates[j]) {
 
-	for record := range change.Iterate(state) {
+				if err := c.send(ctx, domain, http.MethodPatch, creates[j]); err != nil {
+					return err
+				}
 
-		if err := json.NewEncoder(buf).Encode(&DNSEntry{Entry: MarshallDNSRecords(record, domain)}); err != nil {
+				deletes = append(deletes[:i], deletes[i+1:]...)
+				creates = append(creates[:j], creates[j+1:]...)
+				i--
+				break
+			}
+		}
+	}
+
+	for _, entry := range deletes {
+		if err := c.send(ctx, domain, http.MethodDelete, entry); err != nil {
 			return err
 		}
+	}
 
-		if err := c.fetch(ctx, c.toDnsPath(domain), method, buf, nil); err != nil {
+	for _, entry := range creates {
+		if err := c.send(ctx, domain, http.MethodPost, entry); err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
+
+func (c *client) send(ctx context.Context, domain string, method string, entry *DNSRecord) error {
+	var buf = c.buf.Get().(*buf)
+
+	defer buf.Close()
+
+	if err := json.NewEncoder(buf).Encode(&DNSEntry{Entry: entry}); err != nil {
+		return err
+	}
+
+	if err := c.fetch(ctx, c.toDnsPath(domain), method, buf, nil); err != nil {
+
+		if x, ok := err.(ErrorResponse); ok {
+			x.Record = entry
+			return x
+		}
 
-		buf.Reset()
+		return err
 	}
 
 	return nil
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/libdns/libdns"
+	"github.com/pbergman/provider"
+)
+
+// ConflictPolicy defines what to do when a zone was modified by someone else
+// between fetching the records and replacing the zone in FullZoneControl.
+type ConflictPolicy uint8
+
+const (
+	// ConflictOverwrite (default) replaces the zone without checking
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictAbort returns an ErrZoneModified error when the zone changed
+	ConflictAbort
+	// ConflictRebase applies the changes on top of the modified zone
+	ConflictRebase
+)
+
+const DefaultConflictRetries = 3
+
+var ErrZoneModified = fmt.Errorf("zone was modified concurrently: %w", ErrConflict)
+
+func (c *ConflictPolicy) UnmarshalJSON(b []byte) error {
+	var x int
+
+	if err := json.Unmarshal(b, &x); err == nil {
+		*c = ConflictPolicy(x)
+		return nil
+	}
+
+	var z string
+
+	if err := json.Unmarshal(b, &z); err != nil {
+		return errors.New("invalid conflict policy")
+	}
+
+	switch strings.ToLower(z) {
+	case "overwrite", "":
+		*c = ConflictOverwrite
+	case "abort":
+		*c = ConflictAbort
+	case "rebase":
+		*c = ConflictRebase
+	default:
+		return fmt.Errorf("invalid conflict policy \"%s\"", z)
+	}
+
+	return nil
+}
+
+// ConfigConflictPolicy can be implemented to enable the optimistic
+// concurrency check for FullZoneControl, the retries limit how many
+// times the changes are rebased before giving up.
+type ConfigConflictPolicy interface {
+	GetConflictPolicy() ConflictPolicy
+	GetConflictRetries() int
+}
+
+// Fingerprint returns a hash of the entries that does not depend on
+// the order of the entries or the notation of the content.
+func Fingerprint(entries []*DNSRecord) string {
+	var lines = make([]string, len(entries))
+
+	for i, entry := range entries {
+		lines[i] = fmt.Sprintf("%s\x00%s\x00%d\x00%s", strings.ToLower(entry.Name), entry.Type, entry.Expire, canonical(entry))
+	}
+
+	slices.Sort(lines)
+
+	var hash = sha256.Sum256([]byte(strings.Join(lines, "\n")))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// replaceZone will replace all entries of the zone with the new list of the
+// change. When a conflict policy is configured, the zone is fetched again
+// and compared against the zone the change was computed from, right before
+// the zone is replaced.
+func (c *client) replaceZone(ctx context.Context, domain string, change provider.ChangeList) error {
+	var entries = marshallChanges(change, provider.NoChange|provider.Create, domain)
+	var policy, retries = ConflictOverwrite, DefaultConflictRetries
+
+	if v, o := c.config.(ConfigConflictPolicy); o {
+		policy = v.GetConflictPolicy()
+
+		if v.GetConflictRetries() > 0 {
+			retries = v.GetConflictRetries()
+		}
+	}
+
+	if ConflictOverwrite == policy {
+		return c.putZone(ctx, domain, entries)
+	}
+
+	var base = Fingerprint(marshallChanges(change, provider.NoChange|provider.Delete, domain))
+
+	for attempt := 0; ; attempt++ {
+		var data DNSEntries
+
+		if err := c.fetch(ctx, c.toDnsPath(domain), http.MethodGet, nil, &data); err != nil {
+			return err
+		}
+
+		var curr = Fingerprint(data.Entries)
+
+		if curr == base {
+			return c.putZone(ctx, domain, entries)
+		}
+
+		if ConflictAbort == policy || attempt >= retries {
+			return fmt.Errorf("%w: %s", ErrZoneModified, strings.TrimSuffix(domain, "."))
+		}
+
+		base, entries = curr, rebase(data.Entries, change, domain)
+	}
+}
+
+// rebase applies the deletes and creates of the change on given entries
+func rebase(entries []*DNSRecord, change provider.ChangeList, domain string) []*DNSRecord {
+	var deletes = marshallChanges(change, provider.Delete, domain)
+	var list = make([]*DNSRecord, 0, len(entries))
+	var equal = func(a, b *DNSRecord) bool {
+		return strings.EqualFold(a.Name, b.Name) && a.Type == b.Type && canonical(a) == canonical(b)
+	}
+
+	for _, entry := range entries {
+		if false == slices.ContainsFunc(deletes, func(x *DNSRecord) bool { return equal(x, entry) && x.Expire == entry.Expire }) {
+			list = append(list, entry)
+		}
+	}
+
+	for _, entry := range marshallChanges(change, provider.Create, domain) {
+		if false == slices.ContainsFunc(list, func(x *DNSRecord) bool { return equal(x, entry) }) {
+			list = append(list, entry)
+		}
+	}
+
+	return list
+}
+
+// canonical returns the content as formatted by the typed record, so the
+// entries from the api and the change can be compared whatever notation
+// (case, quoting etc.) is used. Content that can't be parsed is returned
+// as is.
+func canonical(entry *DNSRecord) string {
+	if record, err := parseRecord(libdns.RR{Name: entry.Name, Type: entry.Type, Data: entry.Content}); err == nil {
+		return record.RR().Data
+	}
+
+	return entry.Content
+}
+
+func (c *client) putZone(ctx context.Context, domain string, entries []*DNSRecord) error {
+	var buffer = c.buf.Get().(*buf)
+
+	defer buffer.Close()
+
+	if err := json.NewEncoder(buffer).Encode(&DNSEntries{Entries: entries}); err != nil {
+		return err
+	}
+
+	return c.fetch(ctx, c.toDnsPath(domain), http.MethodPut, buffer, nil)
+}
+
+func marshallChanges(change provider.ChangeList, state provider.ChangeState, domain string) []*DNSRecord {
+	var entries = make([]*DNSRecord, 0)
+
+	for record := range change.Iterate(state) {
+		entries = append(entries, MarshallDNSRecords(record, domain))
+	}
+
+	return entries
+}
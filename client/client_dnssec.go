@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DNSSecFlags as accepted by the api
+const (
+	DNSSecFlagsNone uint16 = 0
+	DNSSecFlagsZSK  uint16 = 256
+	DNSSecFlagsKSK  uint16 = 257
+)
+
+// DNSSecAlgorithms holds the DNSKEY algorithms (RFC 8624) accepted by the api
+var DNSSecAlgorithms = map[uint8]string{
+	3:  "DSA/SHA1",
+	5:  "RSA/SHA-1",
+	6:  "DSA-NSEC3-SHA1",
+	7:  "RSASHA1-NSEC3-SHA1",
+	8:  "RSA/SHA-256",
+	10: "RSA/SHA-512",
+	12: "GOST R 34.10-2001",
+	13: "ECDSA Curve P-256 with SHA-256",
+	14: "ECDSA Curve P-384 with SHA-384",
+	15: "Ed25519",
+	16: "Ed448",
+}
+
+type DNSSecEntries struct {
+	Entries []*DNSSecEntry `json:"dnsSecEntries"`
+}
+
+type DNSSecEntry struct {
+	KeyTag    uint16 `json:"keyTag"`
+	Flags     uint16 `json:"flags"`
+	Algorithm uint8  `json:"algorithm"`
+	PublicKey string `json:"publicKey"`
+}
+
+// Validate checks the flags, algorithm and public key so invalid entries
+// are rejected before they are sent to the api.
+func (d *DNSSecEntry) Validate() error {
+
+	switch d.Flags {
+	case DNSSecFlagsNone, DNSSecFlagsZSK, DNSSecFlagsKSK:
+	default:
+		return fmt.Errorf("%w: dnssec flags %d not supported, expecting %d, %d or %d", ErrInvalidRecord, d.Flags, DNSSecFlagsNone, DNSSecFlagsZSK, DNSSecFlagsKSK)
+	}
+
+	if _, ok := DNSSecAlgorithms[d.Algorithm]; false == ok {
+		return fmt.Errorf("%w: dnssec algorithm %d not supported", ErrInvalidRecord, d.Algorithm)
+	}
+
+	if "" == strings.TrimSpace(d.PublicKey) {
+		return fmt.Errorf("%w: dnssec public key is empty", ErrInvalidRecord)
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(d.PublicKey), "")); err != nil {
+		return fmt.Errorf("%w: dnssec public key is not valid base64: %s", ErrInvalidRecord, err)
+	}
+
+	return nil
+}
+
+// DNSSecClient is implemented by clients that can manage the DNSSEC
+// key material of a domain.
+type DNSSecClient interface {
+	GetDNSSEC(ctx context.Context, domain string) ([]*DNSSecEntry, error)
+	SetDNSSEC(ctx context.Context, domain string, entries []*DNSSecEntry) error
+}
+
+func (c *client) toDnsSecPath(domain string) string {
+	return fmt.Sprintf("domains/%s/dnssec", url.PathEscape(strings.TrimSuffix(domain, ".")))
+}
+
+func (c *client) GetDNSSEC(ctx context.Context, domain string) ([]*DNSSecEntry, error) {
+	var data DNSSecEntries
+
+	if err := c.fetch(ctx, c.toDnsSecPath(domain), http.MethodGet, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return data.Entries, nil
+}
+
+// SetDNSSEC replaces all the DNSSEC entries of the domain, which will
+// enable DNSSEC for domains that have none configured yet.
+func (c *client) SetDNSSEC(ctx context.Context, domain string, entries []*DNSSecEntry) error {
+
+	if err := c.guardWrite(ctx); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			return err
+		}
+	}
+
+	var data = &DNSSecEntries{Entries: entries}
+
+	if nil == data.Entries {
+		data.Entries = make([]*DNSSecEntry, 0)
+	}
+
+	var buffer = c.buf.Get().(*buf)
+
+	defer buffer.Close()
+
+	if err := json.NewEncoder(buffer).Encode(data); err != nil {
+		return err
+	}
+
+	return c.fetch(ctx, c.toDnsSecPath(domain), http.MethodPut, buffer, nil)
+}
@@ -2,11 +2,16 @@ package client
 
 import (
 	"context"
+	"iter"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/pbergman/provider"
 )
 
+const DefaultDomainsPageSize = 100
+
 type Domain struct {
 	Name string `json:"name"`
 }
@@ -17,22 +22,103 @@ func (d DomainName) Name() string {
 	return string(d)
 }
 
+// DomainIterator is implemented by clients that can stream the domains
+// page by page instead of loading the whole list in memory.
+type DomainIterator interface {
+	IterateDomains(ctx context.Context) iter.Seq2[provider.Domain, error]
+}
+
 func (c *client) Domains(ctx context.Context) ([]provider.Domain, error) {
 
-	var data struct {
-		Domains []*Domain `json:"domains"`
-		Links   *Links    `json:"_links"`
+	var domains = make([]provider.Domain, 0)
+
+	for domain, err := range c.IterateDomains(ctx) {
+
+		if err != nil {
+			return nil, err
+		}
+
+		domains = append(domains, domain)
 	}
 
-	if err := c.fetch(ctx, "domains", http.MethodGet, nil, &data); err != nil {
-		return nil, err
+	return domains, nil
+}
+
+// IterateDomains will fetch the domains page by page, following the next
+// links until exhausted or the configured limit has been reached. The
+// context is checked between pages so a cancel stops the iteration.
+func (c *client) IterateDomains(ctx context.Context) iter.Seq2[provider.Domain, error] {
+	return func(yield func(provider.Domain, error) bool) {
+		var size, limit = DefaultDomainsPageSize, 0
+
+		if v, o := c.config.(ConfigDomainsPagination); o {
+			if v.DomainsPageSize() > 0 {
+				size = v.DomainsPageSize()
+			}
+
+			limit = v.DomainsLimit()
+		}
+
+		var count = 0
+
+		for page := 1; page > 0; {
+
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			var data struct {
+				Domains []*Domain `json:"domains"`
+				Links   Links     `json:"_links"`
+			}
+
+			var query = url.Values{
+				"page":     {strconv.Itoa(page)},
+				"pageSize": {strconv.Itoa(size)},
+			}
+
+			if err := c.fetch(ctx, "domains?"+query.Encode(), http.MethodGet, nil, &data); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, domain := range data.Domains {
+
+				if false == yield(DomainName(domain.Name), nil) {
+					return
+				}
+
+				if count++; limit > 0 && count >= limit {
+					return
+				}
+			}
+
+			page = nextPage(data.Links, page)
+		}
+	}
+}
+
+// nextPage returns the page number from the next link or 0 when there
+// is no (valid) next page
+func nextPage(links Links, curr int) int {
+	var next = links.Get("next")
+
+	if nil == next {
+		return 0
 	}
 
-	var domains = make([]provider.Domain, len(data.Domains))
+	uri, err := url.Parse(next.Link)
 
-	for i, domain := range data.Domains {
-		domains[i] = DomainName(domain.Name)
+	if err != nil {
+		return 0
 	}
 
-	return domains, nil
+	page, err := strconv.Atoi(uri.Query().Get("page"))
+
+	if err != nil || page <= curr {
+		return 0
+	}
+
+	return page
 }
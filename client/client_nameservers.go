@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+type Nameservers struct {
+	Nameservers []*Nameserver `json:"nameservers"`
+}
+
+type Nameserver struct {
+	Hostname string `json:"hostname"`
+	IPv4     string `json:"ipv4,omitempty"`
+	IPv6     string `json:"ipv6,omitempty"`
+}
+
+// DefaultNameservers returns the TransIP nameservers, which can be used to
+// switch a domain back from external nameservers.
+func DefaultNameservers() []*Nameserver {
+	return []*Nameserver{
+		{Hostname: "ns0.transip.net"},
+		{Hostname: "ns1.transip.nl"},
+		{Hostname: "ns2.transip.eu"},
+	}
+}
+
+// Validate checks the nameserver for given domain, glue records (ipv4
+// and/or ipv6) are required when the hostname is inside the domain and
+// not allowed otherwise.
+func (n *Nameserver) Validate(domain string) error {
+	var hostname = strings.ToLower(strings.TrimSuffix(n.Hostname, "."))
+	var zone = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	if "" == hostname {
+		return fmt.Errorf("%w: nameserver hostname is empty", ErrInvalidRecord)
+	}
+
+	var glue = "" != n.IPv4 || "" != n.IPv6
+
+	if hostname == zone || strings.HasSuffix(hostname, "."+zone) {
+		if false == glue {
+			return fmt.Errorf("%w: nameserver %s is inside %s and requires an ipv4 and/or ipv6 glue record", ErrInvalidRecord, n.Hostname, zone)
+		}
+	} else if glue {
+		return fmt.Errorf("%w: nameserver %s is outside %s and can not have glue records", ErrInvalidRecord, n.Hostname, zone)
+	}
+
+	if "" != n.IPv4 {
+		if addr, err := netip.ParseAddr(n.IPv4); err != nil || false == addr.Is4() {
+			return fmt.Errorf("%w: invalid ipv4 address \"%s\" for nameserver %s", ErrInvalidRecord, n.IPv4, n.Hostname)
+		}
+	}
+
+	if "" != n.IPv6 {
+		if addr, err := netip.ParseAddr(n.IPv6); err != nil || false == addr.Is6() || addr.Is4In6() {
+			return fmt.Errorf("%w: invalid ipv6 address \"%s\" for nameserver %s", ErrInvalidRecord, n.IPv6, n.Hostname)
+		}
+	}
+
+	return nil
+}
+
+// NameserverClient is implemented by clients that can manage the
+// nameservers of a domain.
+type NameserverClient interface {
+	GetNameservers(ctx context.Context, domain string) ([]*Nameserver, error)
+	SetNameservers(ctx context.Context, domain string, nameservers []*Nameserver) error
+}
+
+func (c *client) toNameserversPath(domain string) string {
+	return fmt.Sprintf("domains/%s/nameservers", url.PathEscape(strings.TrimSuffix(domain, ".")))
+}
+
+func (c *client) GetNameservers(ctx context.Context, domain string) ([]*Nameserver, error) {
+	var data Nameservers
+
+	if err := c.fetch(ctx, c.toNameserversPath(domain), http.MethodGet, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return data.Nameservers, nil
+}
+
+// SetNameservers replaces the nameservers of the domain, all nameservers
+// are validated before anything is sent to the api.
+func (c *client) SetNameservers(ctx context.Context, domain string, nameservers []*Nameserver) error {
+
+	if err := c.guardWrite(ctx); err != nil {
+		return err
+	}
+
+	if len(nameservers) == 0 {
+		return fmt.Errorf("%w: at least one nameserver is required", ErrInvalidRecord)
+	}
+
+	for _, nameserver := range nameservers {
+		if err := nameserver.Validate(domain); err != nil {
+			return err
+		}
+	}
+
+	var buffer = c.buf.Get().(*buf)
+
+	defer buffer.Close()
+
+	if err := json.NewEncoder(buffer).Encode(&Nameservers{Nameservers: nameservers}); err != nil {
+		return err
+	}
+
+	return c.fetch(ctx, c.toNameserversPath(domain), http.MethodPut, buffer, nil)
+}
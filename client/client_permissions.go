@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Permissions describes what can be done with the configured key
+type Permissions struct {
+	// Read is true when the api could be reached with the key
+	Read bool
+	// Write is true when the key is not read-only
+	Write bool
+	// GlobalKey is true when the key can be used from any ip address,
+	// otherwise the requests are restricted to the whitelist
+	GlobalKey bool
+	// Whitelisted is false when the api denied the request because the
+	// ip address is not on the whitelist of the key
+	Whitelisted bool
+}
+
+type PermissionChecker interface {
+	// CheckPermissions authenticates and does a test request to report
+	// the abilities of the key. Failures that tell something about the
+	// permissions (like ErrNotWhitelisted) are reported in the returned
+	// Permissions and other errors are returned.
+	CheckPermissions(ctx context.Context) (*Permissions, error)
+	// Writable returns an error that wraps ErrReadOnly when the config or
+	// the stored token is read-only, without sending any request.
+	Writable() error
+}
+
+func (c *client) CheckPermissions(ctx context.Context) (*Permissions, error) {
+	var permissions = &Permissions{Whitelisted: true}
+
+	if err := c.Ping(ctx); err != nil {
+
+		if errors.Is(err, ErrNotWhitelisted) {
+			permissions.Whitelisted = false
+			return permissions, nil
+		}
+
+		return nil, err
+	}
+
+	token, err := c.tokens.getToken(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	permissions.Read = true
+	permissions.Write = false == token.ReadOnly()
+	permissions.GlobalKey = token.GlobalKey()
+
+	return permissions, nil
+}
+
+func (c *client) Writable() error {
+
+	if c.config.ReadOnly() {
+		return fmt.Errorf("key is configured as read-only: %w", ErrReadOnly)
+	}
+
+	if token, err := c.tokens.storage.Get(c.config.StorageKey()); err == nil && nil != token && false == token.IsExpired() && token.ReadOnly() {
+		return fmt.Errorf("token is read-only: %w", ErrReadOnly)
+	}
+
+	return nil
+}
+
+// guardWrite refuses writes for read-only keys, unless the changes are
+// only planned (see WithDryRun).
+func (c *client) guardWrite(ctx context.Context) error {
+
+	if nil != DryRun(ctx) {
+		return nil
+	}
+
+	return c.Writable()
+}
@@ -1,17 +1,81 @@
 package client
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	fallback "math/rand/v2"
 	"net/url"
+	"time"
 
 	"github.com/pbergman/provider"
 )
 
 type ExpirationTime string
 
+// UnmarshalJSON accepts the expiration time in the api format ("1 hour")
+// and as duration ("36h", "90m"), see NewExpirationTime. A duration that
+// is zero or negative returns an error.
+func (e *ExpirationTime) UnmarshalJSON(data []byte) error {
+	var value string
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+
+		if duration <= 0 {
+			return fmt.Errorf("invalid expiration time \"%s\", the duration should be positive", value)
+		}
+
+		*e = NewExpirationTime(duration)
+		return nil
+	}
+
+	*e = ExpirationTime(value)
+
+	return nil
+}
+
+// NewExpirationTime returns the expiration time for the duration, using
+// the largest unit that fits exactly (so 36 hours will be "36 hours" and
+// 90 minutes "90 minutes"). The duration is rounded up to whole seconds,
+// zero or negative durations return an empty value so the default is used.
+func NewExpirationTime(duration time.Duration) ExpirationTime {
+
+	if duration <= 0 {
+		return ""
+	}
+
+	var seconds = int64((duration + time.Second - 1) / time.Second)
+	var value, unit = seconds, "second"
+
+	for _, x := range []struct {
+		size int64
+		name string
+	}{
+		{7 * 24 * 3600, "week"},
+		{24 * 3600, "day"},
+		{3600, "hour"},
+		{60, "minute"},
+	} {
+		if seconds%x.size == 0 {
+			value, unit = seconds/x.size, x.name
+			break
+		}
+	}
+
+	if value != 1 {
+		unit += "s"
+	}
+
+	return ExpirationTime(fmt.Sprintf("%d %s", value, unit))
+}
+
 type DebugLevel uint8
 
 const (
@@ -39,6 +103,29 @@ type Config interface {
 	provider.DebugConfig
 }
 
+// ConfigSigner can be implemented to sign the authentication requests with
+// a crypto.Signer (backed by a HSM, agent or KMS for example) so the private
+// key doesn't have to be loaded in memory. The public key of the signer must
+// be an rsa key, when nil is returned GetPrivateKey is used.
+type ConfigSigner interface {
+	GetSigner() (crypto.Signer, error)
+}
+
+// ConfigKeyRotation can be implemented to authenticate with one of several
+// candidate keys, so keys can be rotated without downtime. The keys are
+// tried in order, starting with the key that was accepted last, and a key
+// is skipped when it can't be loaded or is rejected by the api (bad
+// signature or expired). When KeyCount returns zero ConfigSigner and
+// GetPrivateKey are used.
+type ConfigKeyRotation interface {
+	KeyCount() int
+	GetKey(index int) (crypto.Signer, error)
+	KeyAccepted(index int)
+	KeyRejected(index int, err error)
+}
+
+// ConfigLabel can be implemented to set the label of new tokens (shown in
+// the control panel), an empty label will use the default.
 type ConfigLabel interface {
 	Label() string
 }
@@ -47,10 +134,27 @@ type ConfigExpirationTime interface {
 	ExpirationTime() ExpirationTime
 }
 
+// ConfigNonce can be implemented to generate the nonce of authentication
+// requests (for testing), an empty nonce will use a random one.
 type ConfigNonce interface {
 	Nonce() string
 }
 
+// ConfigRateLimit can be implemented to set the number of remaining requests
+// at which the client starts to spread the requests over the time left until
+// the quota resets. A negative value disables the proactive throttling.
+type ConfigRateLimit interface {
+	GetRateLimitThreshold() int
+}
+
+// ConfigDomainsPagination can be implemented to control the page size used
+// when fetching the domains and to cap the number of returned domains. A
+// value of zero or less will use the default page size and no limit.
+type ConfigDomainsPagination interface {
+	DomainsPageSize() int
+	DomainsLimit() int
+}
+
 func random(size int) (s string) {
 	var buf = make([]byte, size)
 
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewExpirationTime(t *testing.T) {
+	for duration, expect := range map[time.Duration]ExpirationTime{
+		time.Second:                      "1 second",
+		time.Nanosecond:                  "1 second",
+		59 * time.Second:                 "59 seconds",
+		time.Minute:                      "1 minute",
+		90 * time.Minute:                 "90 minutes",
+		24 * time.Hour:                   "1 day",
+		7 * 24 * time.Hour:               "1 week",
+		4 * 7 * 24 * time.Hour:           "4 weeks",
+		8 * 24 * time.Hour:               "8 days",
+		time.Hour + 500*time.Millisecond: "3601 seconds",
+		0:                                "",
+		-time.Hour:                       "",
+	} {
+		if x := NewExpirationTime(duration); x != expect {
+			t.Fatalf("expecting %q for %s got %q", expect, duration, x)
+		}
+	}
+}
+
+func TestExpirationTime_UnmarshalJSON(t *testing.T) {
+	for data, expect := range map[string]ExpirationTime{
+		`"1 hour"`:   ExpirationTime1Hour,
+		`"4 weeks"`:  ExpirationTime4Week,
+		`"36h"`:      "36 hours",
+		`"90m"`:      "90 minutes",
+		`"1h30m10s"`: "5410 seconds",
+		`""`:         "",
+	} {
+		var x ExpirationTime
+
+		if err := json.Unmarshal([]byte(data), &x); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+
+		if x != expect {
+			t.Fatalf("%s: expecting %q got %q", data, expect, x)
+		}
+	}
+
+	for _, data := range []string{`"0s"`, `"-1h"`, `"-90m"`, `3600`} {
+		var x ExpirationTime
+
+		if err := json.Unmarshal([]byte(data), &x); err == nil {
+			t.Fatalf("%s: expecting error got %q", data, x)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Operation is a mutating api request that was not executed because the
+// context was in dry run mode.
+type Operation struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+func (o *Operation) String() string {
+
+	if len(o.Body) == 0 {
+		return fmt.Sprintf("%s %s", o.Method, o.Path)
+	}
+
+	return fmt.Sprintf("%s %s %s", o.Method, o.Path, o.Body)
+}
+
+// Plan holds the operations that would have been executed. Empty and
+// String can be called while requests are still recorded, Operations
+// should only be read when all requests are finished.
+type Plan struct {
+	Operations []*Operation `json:"operations"`
+	mutex      sync.Mutex
+}
+
+func (p *Plan) add(operation *Operation) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.Operations = append(p.Operations, operation)
+}
+
+// Empty returns true when no changes would be made
+func (p *Plan) Empty() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.Operations) == 0
+}
+
+func (p *Plan) String() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var buf strings.Builder
+
+	for _, operation := range p.Operations {
+		buf.WriteString(operation.String())
+		buf.WriteByte('\n')
+	}
+
+	return buf.String()
+}
+
+// WithDryRun returns a context for which all mutating requests (PUT, POST,
+// PATCH and DELETE) are recorded in the returned plan instead of being sent
+// to the api. Read requests are still executed, so the change set can be
+// computed against the current zone.
+//
+//	ctx, plan := client.WithDryRun(context.Background())
+//
+//	if _, err := provider.SetRecords(ctx, "example.com.", records); err != nil {
+//		panic(err)
+//	}
+//
+//	fmt.Print(plan)
+func WithDryRun(ctx context.Context) (context.Context, *Plan) {
+	var plan = new(Plan)
+	return context.WithValue(ctx, "dry_run", plan), plan
+}
+
+// DryRun returns the plan of the context or nil when not in dry run mode
+func DryRun(ctx context.Context) *Plan {
+	return ContextValue[*Plan](ctx, "dry_run", nil)
+}
+
+func (p *Plan) record(method, path string, body io.Reader) error {
+	var operation = &Operation{Method: method, Path: path}
+
+	if nil != body {
+		data, err := io.ReadAll(body)
+
+		if err != nil {
+			return err
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			operation.Body = json.RawMessage(data)
+		}
+	}
+
+	p.add(operation)
+
+	return nil
+}
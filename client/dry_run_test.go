@@ -0,0 +1,41 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	var plan = new(Plan)
+	var group sync.WaitGroup
+
+	if false == plan.Empty() {
+		t.Fatal("expecting empty plan")
+	}
+
+	for i := 0; i < 10; i++ {
+		group.Add(2)
+
+		go func() {
+			defer group.Done()
+
+			if err := plan.record(http.MethodDelete, "domains/example.com/dns", strings.NewReader(" {\"dnsEntry\":{}}\n")); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		go func() {
+			defer group.Done()
+			_ = plan.String()
+			_ = plan.Empty()
+		}()
+	}
+
+	group.Wait()
+
+	if len(plan.Operations) != 10 || plan.Operations[0].String() != `DELETE domains/example.com/dns {"dnsEntry":{}}` {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrConflict       = errors.New("conflict")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrForbidden      = errors.New("forbidden")
+	ErrReadOnly       = errors.New("read-only")
+	ErrNotWhitelisted = errors.New("ip not whitelisted")
+	ErrInvalidRecord  = errors.New("invalid record")
+	ErrServer         = errors.New("server error")
+
+	// authentication errors, which all unwrap to ErrUnauthorized
+	ErrBadSignature = fmt.Errorf("signature rejected: %w", ErrUnauthorized)
+	ErrUnknownLogin = fmt.Errorf("unknown login: %w", ErrUnauthorized)
+	ErrKeyExpired   = fmt.Errorf("key expired: %w", ErrUnauthorized)
+	ErrClockSkew    = fmt.Errorf("clock skew: %w", ErrUnauthorized)
+)
+
+// ErrorResponse is returned for every non 2xx response of the api and
+// unwraps to one of the Err* sentinels, so it can be checked like:
+//
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+//
+//	var response client.ErrorResponse
+//
+//	if errors.As(err, &response) {
+//		fmt.Println(response.Code, response.Method, response.Path)
+//	}
+type ErrorResponse struct {
+	Message string `json:"error"`
+	Code    int    `json:"-"`
+	// Method and Path of the request that failed
+	Method string `json:"-"`
+	Path   string `json:"-"`
+	// Record is the dns entry that was sent with the
+	// request, nil when the request was not record specific
+	Record *DNSRecord `json:"-"`
+	// Attempts is the number of times the request was sent
+	Attempts int `json:"-"`
+
+	retryAfter time.Duration
+}
+
+func (e ErrorResponse) Error() string {
+
+	if "" == e.Method {
+		return e.Message
+	}
+
+	var message = fmt.Sprintf("%s %s: %s (%d)", e.Method, e.Path, e.Message, e.Code)
+
+	if nil != e.Record {
+		message += fmt.Sprintf(" [%s %d %s %s]", e.Record.Name, e.Record.Expire, e.Record.Type, e.Record.Content)
+	}
+
+	if e.Attempts > 1 {
+		message += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+
+	return message
+}
+
+// Unwrap returns the sentinel error that matches the status code and
+// message of the response or nil when none matches
+func (e ErrorResponse) Unwrap() error {
+
+	if strings.HasSuffix(e.Path, "/auth") {
+		return e.unwrapAuth()
+	}
+
+	switch e.Code {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		var message = strings.ToLower(e.Message)
+
+		switch {
+		case strings.Contains(message, "whitelist"):
+			return ErrNotWhitelisted
+		case strings.Contains(message, "read-only"), strings.Contains(message, "read only"), strings.Contains(message, "readonly"):
+			return ErrReadOnly
+		default:
+			return ErrForbidden
+		}
+	case http.StatusBadRequest, http.StatusNotAcceptable, http.StatusUnprocessableEntity:
+		return ErrInvalidRecord
+	}
+
+	if e.Code >= http.StatusInternalServerError {
+		return ErrServer
+	}
+
+	return nil
+}
+
+// unwrapAuth returns the sentinel for a failed authentication request,
+// which are distinguished by the message.
+func (e ErrorResponse) unwrapAuth() error {
+	var message = strings.ToLower(e.Message)
+
+	switch {
+	case e.Code == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.Code >= http.StatusInternalServerError:
+		return ErrServer
+	case strings.Contains(message, "whitelist"):
+		return ErrNotWhitelisted
+	case strings.Contains(message, "signature"):
+		return ErrBadSignature
+	case strings.Contains(message, "login"):
+		return ErrUnknownLogin
+	case strings.Contains(message, "expired"), strings.Contains(message, "revoked"), strings.Contains(message, "disabled"):
+		return ErrKeyExpired
+	case strings.Contains(message, "clock"), strings.Contains(message, "skew"):
+		return ErrClockSkew
+	case e.Code == http.StatusForbidden:
+		return ErrForbidden
+	default:
+		return ErrUnauthorized
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultLabelTemplate is used for the label of new tokens when no
+// template is configured.
+const DefaultLabelTemplate = "libdns client - {random}"
+
+// FormatLabel returns the label for a new token from the template, which
+// can hold the placeholders:
+//
+//	{hostname}  the hostname of the machine
+//	{process}   the name of the executable
+//	{pid}       the process id
+//	{purpose}   the given purpose
+//	{random}    a random string
+//
+// TransIP requires labels to be unique, so " - {random}" is appended when
+// the template has no {random} placeholder.
+func FormatLabel(template, purpose string) string {
+
+	if "" == template {
+		template = DefaultLabelTemplate
+	}
+
+	if false == strings.Contains(template, "{random}") {
+		template += " - {random}"
+	}
+
+	var hostname, _ = os.Hostname()
+	var process string
+
+	if x, err := os.Executable(); err == nil {
+		process = filepath.Base(x)
+	}
+
+	return strings.NewReplacer(
+		"{hostname}", hostname,
+		"{process}", process,
+		"{pid}", strconv.Itoa(os.Getpid()),
+		"{purpose}", purpose,
+		"{random}", random(4),
+	).Replace(template)
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFormatLabel(t *testing.T) {
+	var hostname, _ = os.Hostname()
+	var executable, _ = os.Executable()
+	var pid = strconv.Itoa(os.Getpid())
+
+	for _, x := range []struct {
+		template string
+		purpose  string
+		prefix   string
+		suffix   string
+	}{
+		{"", "", "libdns client - ", ""},
+		{"{hostname} {purpose}", "certificates", hostname + " certificates - ", ""},
+		{"{process}/{pid}", "", filepath.Base(executable) + "/" + pid + " - ", ""},
+		{"{random} for {purpose}", "dns", "", " for dns"},
+		{"static", "", "static - ", ""},
+	} {
+		var label = FormatLabel(x.template, x.purpose)
+
+		if false == strings.HasPrefix(label, x.prefix) || false == strings.HasSuffix(label, x.suffix) {
+			t.Fatalf("%q: expecting label %q...%q got %q", x.template, x.prefix, x.suffix, label)
+		}
+
+		// the random part keeps the labels unique
+		if random := strings.TrimSuffix(strings.TrimPrefix(label, x.prefix), x.suffix); len(random) != 8 || strings.Contains(label, "{") {
+			t.Fatalf("%q: expecting a random string got %q", x.template, label)
+		}
+	}
+
+	if FormatLabel("", "") == FormatLabel("", "") {
+		t.Fatal("expecting unique labels")
+	}
+}
@@ -0,0 +1,263 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// MinKeySize is the minimal size in bits of the rsa keys that are accepted
+const MinKeySize = 2048
+
+var ErrInvalidPrivateKey = errors.New("invalid private key")
+
+// PrivateKeyError is returned when a private key could not be used and
+// holds the detected format, it unwraps to ErrInvalidPrivateKey.
+type PrivateKeyError struct {
+	Format string
+	Err    error
+}
+
+func (e *PrivateKeyError) Error() string {
+	if "" == e.Format {
+		return fmt.Sprintf("invalid private key: %s", e.Err)
+	}
+	return fmt.Sprintf("invalid private key (%s): %s", e.Format, e.Err)
+}
+
+func (e *PrivateKeyError) Unwrap() []error {
+	return []error{ErrInvalidPrivateKey, e.Err}
+}
+
+// IsPrivateKeyData returns true when the data holds a PEM block or a base64
+// encoded PEM block (ignoring surrounding whitespace), and so is not a path.
+func IsPrivateKeyData(data []byte) bool {
+	_, ok := pemData(data)
+	return ok
+}
+
+// pemData returns the PEM encoded data, decoding base64 when needed
+func pemData(data []byte) ([]byte, bool) {
+	data = bytes.TrimSpace(data)
+
+	if bytes.HasPrefix(data, []byte("-----BEGIN ")) {
+		return data, true
+	}
+
+	// base64 encoded PEM, as used for keys in environment variables
+	if decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), "")); err == nil {
+		if decoded = bytes.TrimSpace(decoded); bytes.HasPrefix(decoded, []byte("-----BEGIN ")) {
+			return decoded, true
+		}
+	}
+
+	return nil, false
+}
+
+// ParsePrivateKey parses a PEM (or base64 encoded PEM) rsa private key in
+// the PKCS #1 (BEGIN RSA PRIVATE KEY), PKCS #8 (BEGIN PRIVATE KEY) or
+// passphrase protected PKCS #8 (BEGIN ENCRYPTED PRIVATE KEY) format. The
+// key must be at least MinKeySize bits.
+func ParsePrivateKey(data []byte, passphrase string) (*rsa.PrivateKey, error) {
+	data, ok := pemData(data)
+
+	if false == ok {
+		return nil, &PrivateKeyError{Err: errors.New("no PEM encoded key found")}
+	}
+
+	block, _ := pem.Decode(data)
+
+	if nil == block {
+		return nil, &PrivateKeyError{Format: "PEM", Err: errors.New("failed to decode PEM block")}
+	}
+
+	var format string
+	var key any
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		format = "PKCS #1"
+
+		if _, ok := block.Headers["DEK-Info"]; ok {
+			return nil, &PrivateKeyError{Format: format, Err: errors.New("legacy PEM encryption is not supported, convert it with: openssl pkcs8 -topk8 -v2 aes256")}
+		}
+
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		format = "PKCS #8"
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		format = "encrypted PKCS #8"
+
+		if "" == passphrase {
+			return nil, &PrivateKeyError{Format: format, Err: errors.New("key is encrypted but no passphrase was provided")}
+		}
+
+		var plain []byte
+
+		if plain, err = decryptPKCS8(block.Bytes, passphrase); err == nil {
+			key, err = x509.ParsePKCS8PrivateKey(plain)
+		}
+	case "OPENSSH PRIVATE KEY":
+		return nil, &PrivateKeyError{Format: "OpenSSH", Err: errors.New("format is not supported, convert it with: ssh-keygen -p -m PKCS8")}
+	case "PUBLIC KEY", "RSA PUBLIC KEY", "CERTIFICATE":
+		return nil, &PrivateKeyError{Format: strings.ToLower(block.Type), Err: errors.New("a private key is required")}
+	default:
+		return nil, &PrivateKeyError{Format: block.Type, Err: errors.New("unsupported PEM block type")}
+	}
+
+	if err != nil {
+		return nil, &PrivateKeyError{Format: format, Err: err}
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+
+	if false == ok {
+		return nil, &PrivateKeyError{Format: format, Err: fmt.Errorf("TransIP only supports rsa keys, got %T", key)}
+	}
+
+	if size := rsaKey.N.BitLen(); size < MinKeySize {
+		return nil, &PrivateKeyError{Format: format, Err: fmt.Errorf("key size of %d bits is less than the required %d bits", size, MinKeySize)}
+	}
+
+	if err := rsaKey.Validate(); err != nil {
+		return nil, &PrivateKeyError{Format: format, Err: err}
+	}
+
+	return rsaKey, nil
+}
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// RFC 5208 and RFC 8018
+type encryptedPrivateKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Data      []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// decryptPKCS8 decrypts PBES2 (PBKDF2 with AES-CBC) encrypted keys, which
+// is what current versions of openssl create.
+func decryptPKCS8(data []byte, passphrase string) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	if false == info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported encryption %s, only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+
+	if false == params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation %s, only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, err
+	}
+
+	var size int
+
+	switch {
+	case params.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		size = 16
+	case params.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		size = 24
+	case params.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		size = 32
+	default:
+		return nil, fmt.Errorf("unsupported cipher %s, only AES-CBC is supported", params.EncryptionScheme.Algorithm)
+	}
+
+	var iv []byte
+
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, errors.New("invalid cipher iv")
+	}
+
+	var hasher func() hash.Hash
+
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		hasher = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		hasher = sha256.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		hasher = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 hash %s", kdf.PRF.Algorithm)
+	}
+
+	key, err := pbkdf2.Key(hasher, passphrase, kdf.Salt, kdf.Iterations, size)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(info.Data) == 0 || len(info.Data)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid encrypted data length")
+	}
+
+	var plain = make([]byte, len(info.Data))
+
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.Data)
+
+	// a wrong passphrase will (almost always) result in invalid padding
+	var padding = int(plain[len(plain)-1])
+
+	if padding == 0 || padding > aes.BlockSize || false == bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("failed to decrypt, the passphrase is probably wrong")
+	}
+
+	return plain[:len(plain)-padding], nil
+}
@@ -0,0 +1,443 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ALIAS represents a TransIP ALIAS record, which resolves the target
+// and serves its addresses (can be used on the apex of the zone).
+type ALIAS struct {
+	Name   string
+	TTL    time.Duration
+	Target string
+}
+
+func (a ALIAS) RR() libdns.RR {
+	return libdns.RR{Name: a.Name, TTL: a.TTL, Type: "ALIAS", Data: a.Target}
+}
+
+// NAPTR represents a Naming Authority Pointer record (RFC 3403)
+type NAPTR struct {
+	Name        string
+	TTL         time.Duration
+	Order       uint16
+	Preference  uint16
+	Flags       string
+	Service     string
+	Regexp      string
+	Replacement string
+}
+
+func (n NAPTR) RR() libdns.RR {
+	return libdns.RR{
+		Name: n.Name,
+		TTL:  n.TTL,
+		Type: "NAPTR",
+		Data: fmt.Sprintf("%d %d %s %s %s %s", n.Order, n.Preference, quote(n.Flags), quote(n.Service), quote(n.Regexp), n.Replacement),
+	}
+}
+
+// TLSA represents a DANE TLSA record (RFC 6698)
+type TLSA struct {
+	Name         string
+	TTL          time.Duration
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Certificate  string
+}
+
+func (t TLSA) RR() libdns.RR {
+	return libdns.RR{
+		Name: t.Name,
+		TTL:  t.TTL,
+		Type: "TLSA",
+		Data: fmt.Sprintf("%d %d %d %s", t.Usage, t.Selector, t.MatchingType, t.Certificate),
+	}
+}
+
+// SSHFP represents an SSH fingerprint record (RFC 4255)
+type SSHFP struct {
+	Name        string
+	TTL         time.Duration
+	Algorithm   uint8
+	Type        uint8
+	Fingerprint string
+}
+
+func (s SSHFP) RR() libdns.RR {
+	return libdns.RR{
+		Name: s.Name,
+		TTL:  s.TTL,
+		Type: "SSHFP",
+		Data: fmt.Sprintf("%d %d %s", s.Algorithm, s.Type, s.Fingerprint),
+	}
+}
+
+// DS represents a delegation signer record (RFC 4034)
+type DS struct {
+	Name       string
+	TTL        time.Duration
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     string
+}
+
+func (d DS) RR() libdns.RR {
+	return libdns.RR{
+		Name: d.Name,
+		TTL:  d.TTL,
+		Type: "DS",
+		Data: fmt.Sprintf("%d %d %d %s", d.KeyTag, d.Algorithm, d.DigestType, d.Digest),
+	}
+}
+
+// digest sizes in hex characters
+var (
+	tlsaMatchingTypes = map[uint8]int{0: 0, 1: 64, 2: 128}
+	sshfpAlgorithms   = map[uint8]string{1: "RSA", 2: "DSA", 3: "ECDSA", 4: "Ed25519", 6: "Ed448"}
+	sshfpTypes        = map[uint8]int{1: 40, 2: 64}
+	dsDigestTypes     = map[uint8]int{1: 40, 2: 64, 4: 96}
+)
+
+// parseRecord parses the data of the TransIP specific types that are not
+// modeled by libdns, other types are parsed by libdns. The validation is
+// strict for the records that are created, entries that are already stored
+// and fail to parse are read as RawRecord (see readDNSRecord).
+func parseRecord(rr libdns.RR) (libdns.Record, error) {
+	switch rr.Type {
+	case "ALIAS":
+		return parseALIAS(rr)
+	case "NAPTR":
+		return parseNAPTR(rr)
+	case "TLSA":
+		return parseTLSA(rr)
+	case "SSHFP":
+		return parseSSHFP(rr)
+	case "DS":
+		return parseDS(rr)
+	default:
+		return rr.Parse()
+	}
+}
+
+func parseALIAS(rr libdns.RR) (ALIAS, error) {
+	var target = strings.TrimSpace(rr.Data)
+
+	if "" == target || strings.ContainsAny(target, " \t") {
+		return ALIAS{}, fmt.Errorf("invalid ALIAS target \"%s\"", rr.Data)
+	}
+
+	return ALIAS{Name: rr.Name, TTL: rr.TTL, Target: target}, nil
+}
+
+func parseNAPTR(rr libdns.RR) (NAPTR, error) {
+	fields, err := split(rr.Data)
+
+	if err != nil {
+		return NAPTR{}, err
+	}
+
+	if len(fields) != 6 {
+		return NAPTR{}, fmt.Errorf("malformed NAPTR value; expected 6 fields in the form 'order preference \"flags\" \"service\" \"regexp\" replacement'")
+	}
+
+	order, err := strconv.ParseUint(fields[0], 10, 16)
+
+	if err != nil {
+		return NAPTR{}, fmt.Errorf("invalid order %s: %v", fields[0], err)
+	}
+
+	preference, err := strconv.ParseUint(fields[1], 10, 16)
+
+	if err != nil {
+		return NAPTR{}, fmt.Errorf("invalid preference %s: %v", fields[1], err)
+	}
+
+	for _, c := range fields[2] {
+		if false == strings.ContainsRune("SAUPsaup", c) && (c < '0' || c > '9') {
+			return NAPTR{}, fmt.Errorf("invalid flags \"%s\"", fields[2])
+		}
+	}
+
+	if "" != fields[4] && "." != fields[5] {
+		return NAPTR{}, fmt.Errorf("regexp and replacement are mutually exclusive, replacement should be \".\" when regexp is set")
+	}
+
+	return NAPTR{
+		Name:        rr.Name,
+		TTL:         rr.TTL,
+		Order:       uint16(order),
+		Preference:  uint16(preference),
+		Flags:       fields[2],
+		Service:     fields[3],
+		Regexp:      fields[4],
+		Replacement: fields[5],
+	}, nil
+}
+
+func parseTLSA(rr libdns.RR) (TLSA, error) {
+	var fields = strings.Fields(rr.Data)
+
+	if len(fields) != 4 {
+		return TLSA{}, fmt.Errorf("malformed TLSA value; expected 4 fields in the form 'usage selector matching-type certificate'")
+	}
+
+	usage, err := parseUint8(fields[0], "usage", 3)
+
+	if err != nil {
+		return TLSA{}, err
+	}
+
+	selector, err := parseUint8(fields[1], "selector", 1)
+
+	if err != nil {
+		return TLSA{}, err
+	}
+
+	matching, err := parseUint8(fields[2], "matching type", 2)
+
+	if err != nil {
+		return TLSA{}, err
+	}
+
+	if err := validateHex(fields[3], tlsaMatchingTypes[matching]); err != nil {
+		return TLSA{}, err
+	}
+
+	return TLSA{
+		Name:         rr.Name,
+		TTL:          rr.TTL,
+		Usage:        usage,
+		Selector:     selector,
+		MatchingType: matching,
+		Certificate:  fields[3],
+	}, nil
+}
+
+func parseSSHFP(rr libdns.RR) (SSHFP, error) {
+	var fields = strings.Fields(rr.Data)
+
+	if len(fields) != 3 {
+		return SSHFP{}, fmt.Errorf("malformed SSHFP value; expected 3 fields in the form 'algorithm type fingerprint'")
+	}
+
+	algorithm, err := parseUint8(fields[0], "algorithm", 255)
+
+	if err != nil {
+		return SSHFP{}, err
+	}
+
+	if _, ok := sshfpAlgorithms[algorithm]; false == ok {
+		return SSHFP{}, fmt.Errorf("unsupported algorithm %d", algorithm)
+	}
+
+	kind, err := parseUint8(fields[1], "fingerprint type", 255)
+
+	if err != nil {
+		return SSHFP{}, err
+	}
+
+	size, ok := sshfpTypes[kind]
+
+	if false == ok {
+		return SSHFP{}, fmt.Errorf("unsupported fingerprint type %d", kind)
+	}
+
+	if err := validateHex(fields[2], size); err != nil {
+		return SSHFP{}, err
+	}
+
+	return SSHFP{
+		Name:        rr.Name,
+		TTL:         rr.TTL,
+		Algorithm:   algorithm,
+		Type:        kind,
+		Fingerprint: fields[2],
+	}, nil
+}
+
+func parseDS(rr libdns.RR) (DS, error) {
+	var fields = strings.Fields(rr.Data)
+
+	if len(fields) != 4 {
+		return DS{}, fmt.Errorf("malformed DS value; expected 4 fields in the form 'key-tag algorithm digest-type digest'")
+	}
+
+	tag, err := strconv.ParseUint(fields[0], 10, 16)
+
+	if err != nil {
+		return DS{}, fmt.Errorf("invalid key tag %s: %v", fields[0], err)
+	}
+
+	algorithm, err := parseUint8(fields[1], "algorithm", 255)
+
+	if err != nil {
+		return DS{}, err
+	}
+
+	if _, ok := DNSSecAlgorithms[algorithm]; false == ok {
+		return DS{}, fmt.Errorf("unsupported algorithm %d", algorithm)
+	}
+
+	kind, err := parseUint8(fields[2], "digest type", 255)
+
+	if err != nil {
+		return DS{}, err
+	}
+
+	size, ok := dsDigestTypes[kind]
+
+	if false == ok {
+		return DS{}, fmt.Errorf("unsupported digest type %d", kind)
+	}
+
+	if err := validateHex(fields[3], size); err != nil {
+		return DS{}, err
+	}
+
+	return DS{
+		Name:       rr.Name,
+		TTL:        rr.TTL,
+		KeyTag:     uint16(tag),
+		Algorithm:  algorithm,
+		DigestType: kind,
+		Digest:     fields[3],
+	}, nil
+}
+
+func parseUint8(x string, name string, maximum uint8) (uint8, error) {
+	value, err := strconv.ParseUint(x, 10, 8)
+
+	if err != nil || uint8(value) > maximum {
+		return 0, fmt.Errorf("invalid %s %s, expecting 0..%d", name, x, maximum)
+	}
+
+	return uint8(value), nil
+}
+
+// validateHex checks that the value is hex encoded and of given size,
+// a size of zero means any (non-empty) size.
+func validateHex(x string, size int) error {
+
+	if _, err := hex.DecodeString(x); err != nil || "" == x {
+		return fmt.Errorf("invalid hex value \"%s\"", x)
+	}
+
+	if size > 0 && len(x) != size {
+		return fmt.Errorf("invalid hex value length %d, expecting %d", len(x), size)
+	}
+
+	return nil
+}
+
+// split splits the data on whitespace, keeping quoted strings together
+// (without the quotes).
+func split(data string) ([]string, error) {
+	var fields = make([]string, 0)
+
+	for data = strings.TrimSpace(data); "" != data; data = strings.TrimSpace(data) {
+
+		if data[0] == '"' {
+			value, rest, err := unquote(data)
+
+			if err != nil {
+				return nil, err
+			}
+
+			fields = append(fields, value)
+			data = rest
+
+			continue
+		}
+
+		var end = strings.IndexAny(data, " \t")
+
+		if end < 0 {
+			end = len(data)
+		}
+
+		fields = append(fields, data[:end])
+		data = data[end:]
+	}
+
+	return fields, nil
+}
+
+// quote returns the text as quoted character string in the presentation
+// format of RFC 1035, with quotes and backslashes escaped and \DDD for
+// non-printable bytes.
+func quote(text string) string {
+	var buf strings.Builder
+
+	buf.WriteByte('"')
+
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			_, _ = fmt.Fprintf(&buf, "\\%03d", c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	buf.WriteByte('"')
+
+	return buf.String()
+}
+
+// unquote reads the quoted character string at the start of the data and
+// returns the value and the remaining data. A \DDD escape is read as the
+// byte and any other \X as X (see RFC 1035 section 5.1).
+func unquote(data string) (string, string, error) {
+	var value strings.Builder
+
+	for i := 1; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '"':
+			return value.String(), data[i+1:], nil
+		case '\\':
+			if i+1 >= len(data) {
+				break
+			}
+
+			if x := data[i+1:]; isDigits(x[:1]) {
+				code, err := strconv.Atoi(x[:min(3, len(x))])
+
+				if err != nil || len(x) < 3 || false == isDigits(x[:3]) || code > 255 {
+					return "", "", fmt.Errorf("invalid \\DDD escape sequence in \"%s\"", data)
+				}
+
+				value.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+
+			value.WriteByte(data[i+1])
+			i++
+		default:
+			value.WriteByte(c)
+		}
+	}
+
+	return "", "", fmt.Errorf("invalid quoted string in \"%s\"", data)
+}
+
+func isDigits(x string) bool {
+	for _, c := range x {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestParseRecord(t *testing.T) {
+	for _, record := range []libdns.Record{
+		ALIAS{Name: "@", TTL: time.Hour, Target: "example.net."},
+		NAPTR{Name: "@", TTL: time.Hour, Order: 100, Preference: 10, Flags: "S", Service: "SIP+D2U", Replacement: "_sip._udp.example.com."},
+		NAPTR{Name: "@", TTL: time.Hour, Order: 100, Preference: 10, Flags: "U", Service: "E2U+sip", Regexp: `!^.*$!sip:caf` + "é" + `@example.com!`, Replacement: "."},
+		NAPTR{Name: "@", TTL: time.Hour, Flags: "U", Service: `quote " and \ backslash`, Regexp: "!^.*$!x!", Replacement: "."},
+		TLSA{Name: "_25._tcp.mail", TTL: time.Hour, Usage: 3, Selector: 1, MatchingType: 1, Certificate: "0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6"},
+		SSHFP{Name: "host", TTL: time.Hour, Algorithm: 4, Type: 2, Fingerprint: "0c72ac70b745ac19998811b131d662c9ac69dbdbe7cb23e5b514b56664c5d3d6"},
+		DS{Name: "sub", TTL: time.Hour, KeyTag: 12345, Algorithm: 13, DigestType: 1, Digest: "123456789abcdef0123456789abcdef012345678"},
+	} {
+		parsed, err := parseRecord(record.RR())
+
+		if err != nil {
+			t.Fatalf("failed to parse %#v: %v", record.RR(), err)
+		}
+
+		if parsed != record {
+			t.Fatalf("expecting %#v got %#v", record, parsed)
+		}
+	}
+}
+
+func TestParseRecord_NAPTRQuoting(t *testing.T) {
+	var naptr = NAPTR{Name: "@", Order: 100, Preference: 10, Flags: "U", Service: "E2U+sip", Regexp: "!^.*$!sip:café@example.com!", Replacement: "."}
+
+	if data := naptr.RR().Data; data != `100 10 "U" "E2U+sip" "!^.*$!sip:caf\195\169@example.com!" .` {
+		t.Fatalf("unexpected presentation format %s", data)
+	}
+
+	record, err := parseRecord(libdns.RR{Name: "@", Type: "NAPTR", Data: `100 10 "U" "E2U+sip" "!^.*$!sip:info@example\.com!" .`})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x := record.(NAPTR); x.Regexp != "!^.*$!sip:info@example.com!" {
+		t.Fatalf("unexpected regexp %s", x.Regexp)
+	}
+
+	for _, data := range []string{
+		`100 10 "U" "E2U+sip" "unterminated .`,
+		`100 10 "U" "E2U+sip" "\256" .`,
+		`100 10 "U" "E2U+sip" "\12" .`,
+	} {
+		if _, err := parseRecord(libdns.RR{Name: "@", Type: "NAPTR", Data: data}); err == nil {
+			t.Fatalf("expecting error for %s", data)
+		}
+	}
+}
+
+func TestParseRecord_Invalid(t *testing.T) {
+	for _, rr := range []libdns.RR{
+		{Type: "ALIAS", Data: "two targets"},
+		{Type: "NAPTR", Data: `100 10 "X" "SIP+D2U" "" _sip._udp.example.com.`},
+		{Type: "NAPTR", Data: `100 10 "U" "E2U+sip" "!^.*$!x!" _sip._udp.example.com.`},
+		{Type: "TLSA", Data: "3 1 1 0123456789abcdef"},
+		{Type: "TLSA", Data: "4 1 1 00"},
+		{Type: "SSHFP", Data: "5 1 123456789abcdef0123456789abcdef012345678"},
+		{Type: "SSHFP", Data: "4 2 zz"},
+		{Type: "DS", Data: "12345 13 3 abcd"},
+		{Type: "DS", Data: "70000 13 1 123456789abcdef0123456789abcdef012345678"},
+	} {
+		if _, err := parseRecord(rr); err == nil {
+			t.Fatalf("expecting error for %s %s", rr.Type, rr.Data)
+		}
+	}
+}
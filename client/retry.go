@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultRetryMaxBackoff = 10 * time.Second
+)
+
+// ConfigRetry can be implemented to control the retries of requests that
+// failed because of network errors, server errors (5xx) or because the
+// domain was locked by another process (409). The attempts include the
+// first request, so a value of 1 (or negative) disables retries, and a
+// value of zero will use the defaults.
+type ConfigRetry interface {
+	GetRetryAttempts() int
+	GetRetryBackoff() time.Duration
+	GetRetryMaxBackoff() time.Duration
+}
+
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newRetryPolicy(config Config) *retryPolicy {
+	var policy = &retryPolicy{
+		attempts:   DefaultRetryAttempts,
+		backoff:    DefaultRetryBackoff,
+		maxBackoff: DefaultRetryMaxBackoff,
+	}
+
+	if v, ok := config.(ConfigRetry); ok {
+
+		if x := v.GetRetryAttempts(); x != 0 {
+			policy.attempts = max(x, 1)
+		}
+
+		if x := v.GetRetryBackoff(); x > 0 {
+			policy.backoff = x
+		}
+
+		if x := v.GetRetryMaxBackoff(); x > 0 {
+			policy.maxBackoff = x
+		}
+	}
+
+	return policy
+}
+
+// next returns the time to wait before the next attempt or false when
+// the request should not (or can't within the deadline) be retried.
+func (p *retryPolicy) next(ctx context.Context, method string, attempt int, err error) (time.Duration, bool) {
+
+	if attempt >= p.attempts || false == isRetryable(method, err) {
+		return 0, false
+	}
+
+	var wait = p.delay(attempt)
+
+	// full jitter on the upper half so clients won't retry in lockstep
+	if wait > 1 {
+		wait = wait/2 + rand.N(wait/2)
+	}
+
+	var response ErrorResponse
+
+	if errors.As(err, &response) && response.retryAfter > wait {
+		wait = response.retryAfter
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+// delay returns the backoff before the given retry, which doubles every
+// attempt until the max backoff is reached.
+func (p *retryPolicy) delay(attempt int) time.Duration {
+	var wait = min(p.backoff, p.maxBackoff)
+
+	for i := 1; i < attempt && wait < p.maxBackoff; i++ {
+		// compare with the half so doubling can't overflow
+		if wait > p.maxBackoff/2 {
+			wait = p.maxBackoff
+		} else {
+			wait *= 2
+		}
+	}
+
+	return wait
+}
+
+// isRetryable returns true when the failed request can be sent again, which
+// is the case for idempotent methods or when the request was not processed.
+func isRetryable(method string, err error) bool {
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var response ErrorResponse
+
+	if errors.As(err, &response) {
+		switch {
+		case response.Code == http.StatusConflict:
+			// the domain is locked by another process and the request was denied
+			return strings.Contains(strings.ToLower(response.Message), "another process")
+		case response.Code >= http.StatusInternalServerError && response.Code != http.StatusNotImplemented:
+			return isIdempotent(method)
+		default:
+			return false
+		}
+	}
+
+	var dial *net.OpError
+
+	// the connection could not be made so nothing was sent
+	if errors.As(err, &dial) && "dial" == dial.Op {
+		return true
+	}
+
+	// url.Error implements net.Error itself, so check what it wraps
+	var request *url.Error
+
+	if errors.As(err, &request) {
+		err = request.Err
+	}
+
+	var network net.Error
+
+	return errors.As(err, &network) && isIdempotent(method)
+}
+
+// isIdempotent returns true for methods that give the same result when
+// repeated. DELETE and PATCH are left out because a retry after a lost
+// response fails (404) or could change an entry that was modified since.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses the Retry-After header which holds the delay in
+// seconds or a http date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+
+	if "" == value {
+		return 0, false
+	}
+
+	if x, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(x, 0)) * time.Second, true
+	}
+
+	if x, err := http.ParseTime(value); err == nil {
+		return max(time.Until(x), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	var dial = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	var read = &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+
+	for _, x := range []struct {
+		method string
+		err    error
+		expect bool
+	}{
+		{http.MethodPost, &url.Error{Op: "Post", URL: "dns", Err: dial}, true},
+		{http.MethodGet, &url.Error{Op: "Get", URL: "dns", Err: read}, true},
+		{http.MethodPost, &url.Error{Op: "Post", URL: "dns", Err: read}, false},
+		// url.Error implements net.Error but wraps no network error
+		{http.MethodGet, &url.Error{Op: "Get", URL: "dns", Err: errors.New("failed to sign")}, false},
+		{http.MethodGet, ErrorResponse{Code: http.StatusBadGateway}, true},
+		{http.MethodPost, ErrorResponse{Code: http.StatusBadGateway}, false},
+		{http.MethodPost, ErrorResponse{Code: http.StatusConflict, Message: "Another process is already modifying this domain"}, true},
+		{http.MethodGet, ErrorResponse{Code: http.StatusNotFound}, false},
+		{http.MethodPut, ErrorResponse{Code: http.StatusServiceUnavailable}, true},
+		{http.MethodDelete, ErrorResponse{Code: http.StatusBadGateway}, false},
+		{http.MethodPatch, &url.Error{Op: "Patch", URL: "dns", Err: read}, false},
+		{http.MethodDelete, &url.Error{Op: "Delete", URL: "dns", Err: dial}, true},
+	} {
+		if isRetryable(x.method, x.err) != x.expect {
+			t.Fatalf("expecting retryable %v for %s %v", x.expect, x.method, x.err)
+		}
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	var policy = &retryPolicy{attempts: math.MaxInt, backoff: 500 * time.Millisecond, maxBackoff: 10 * time.Second}
+
+	for attempt, expect := range map[int]time.Duration{
+		1:           500 * time.Millisecond,
+		2:           time.Second,
+		3:           2 * time.Second,
+		5:           8 * time.Second,
+		6:           10 * time.Second,
+		64:          10 * time.Second,
+		1000:        10 * time.Second,
+		math.MaxInt: 10 * time.Second,
+	} {
+		if x := policy.delay(attempt); x != expect {
+			t.Fatalf("expecting %s for attempt %d got %s", expect, attempt, x)
+		}
+	}
+
+	// doubling close to the max duration should not overflow
+	policy = &retryPolicy{attempts: math.MaxInt, backoff: time.Second, maxBackoff: math.MaxInt64}
+
+	for _, attempt := range []int{32, 63, 64, 65, 1000} {
+		if x := policy.delay(attempt); x <= 0 {
+			t.Fatalf("expecting positive delay for attempt %d got %s", attempt, x)
+		}
+	}
+
+	// a backoff above the max is capped
+	policy = &retryPolicy{attempts: math.MaxInt, backoff: time.Minute, maxBackoff: time.Second}
+
+	if x := policy.delay(1); x != time.Second {
+		t.Fatalf("expecting 1s got %s", x)
+	}
+}
+
+func TestRetryPolicy_Next(t *testing.T) {
+	var policy = &retryPolicy{attempts: 3, backoff: time.Second, maxBackoff: 4 * time.Second}
+	var err = ErrorResponse{Code: http.StatusBadGateway}
+
+	for attempt := 1; attempt < 3; attempt++ {
+		wait, ok := policy.next(context.Background(), http.MethodGet, attempt, err)
+
+		// jitter keeps the wait in the upper half of the delay
+		if delay := policy.delay(attempt); false == ok || wait < delay/2 || wait > delay {
+			t.Fatalf("expecting wait between %s and %s for attempt %d got %s (%v)", delay/2, delay, attempt, wait, ok)
+		}
+	}
+
+	if _, ok := policy.next(context.Background(), http.MethodGet, 3, err); ok {
+		t.Fatal("expecting no retry after the last attempt")
+	}
+
+	if wait, ok := policy.next(context.Background(), http.MethodGet, 1, ErrorResponse{Code: http.StatusBadGateway, retryAfter: time.Minute}); false == ok || wait != time.Minute {
+		t.Fatalf("expecting retry after 1m got %s (%v)", wait, ok)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+
+	defer cancel()
+
+	if _, ok := policy.next(ctx, http.MethodGet, 1, err); ok {
+		t.Fatal("expecting no retry beyond the deadline")
+	}
+}
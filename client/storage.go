@@ -1,6 +1,20 @@
 package client
 
+import (
+	"context"
+)
+
 type Storage interface {
 	Set(key string, token Token) error
 	Get(key string) (Token, error)
 }
+
+// LockingStorage is implemented by storages that are shared between
+// processes, so only one of them requests a new token while the others
+// wait and reuse the stored result.
+type LockingStorage interface {
+	Storage
+	// Lock blocks until the lock for given key is acquired or the
+	// context is done and returns a function to release the lock.
+	Lock(ctx context.Context, key string) (func(), error)
+}
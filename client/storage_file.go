@@ -1,18 +1,63 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"encoding/gob"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
+// lockRetryInterval is the interval used to poll a lock held by another process
+const lockRetryInterval = 50 * time.Millisecond
+
 // NewTokenFileStorage will use given directory for storing token sessions
 // and try to create when calling this function
 //
 // storage, err := NewTokenFileStorage(filepath.Join(os.TempDir(), "transip"))
 func NewTokenFileStorage(root string) (Storage, error) {
+	return newStorageFile(root, gobCodec{})
+}
+
+// tokenCodec converts tokens from and to the file content
+type tokenCodec interface {
+	extension() string
+	encode(key string, token Token) ([]byte, error)
+	// decode returns nil (without error) when the content can't be used
+	decode(key string, data []byte) (Token, error)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) extension() string {
+	return ""
+}
+
+func (gobCodec) encode(_ string, token Token) ([]byte, error) {
+	var buf = new(bytes.Buffer)
+
+	if err := gob.NewEncoder(buf).Encode(token); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) decode(_ string, data []byte) (Token, error) {
+	var token Token = new(token)
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func newStorageFile(root string, codec tokenCodec) (*storageFile, error) {
 
 	err := os.MkdirAll(root, 0700)
 
@@ -20,54 +65,71 @@ func NewTokenFileStorage(root string) (Storage, error) {
 		return nil, err
 	}
 
-	return &storageFile{root: root, items: make(map[string]Token)}, nil
+	return &storageFile{root: root, codec: codec, items: make(map[string]*storedToken)}, nil
 }
 
 type storageFile struct {
 	root  string
-	mutex sync.RWMutex
-	items map[string]Token
+	codec tokenCodec
+	mutex sync.Mutex
+	items map[string]*storedToken
+}
+
+// storedToken holds the token with the file info of the file so changes
+// made by other processes are detected.
+type storedToken struct {
+	token Token
+	info  os.FileInfo
+}
+
+// unchanged returns true when the file is the one the token was read from
+// or written to, the modification time alone is not enough because two
+// writes can happen within the resolution of the file system timestamps.
+func (s *storedToken) unchanged(info os.FileInfo) bool {
+	return nil != s.info && os.SameFile(s.info, info) && s.info.Size() == info.Size() && s.info.ModTime().Equal(info.ModTime())
+}
+
+func (s *storageFile) file(key string) string {
+	return filepath.Join(s.root, key+s.codec.extension())
 }
 
 func (s *storageFile) Set(key string, token Token) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.items[key] = token
+	var item = &storedToken{token: token}
+
+	s.items[key] = item
 
-	file, err := os.OpenFile(filepath.Join(s.root, key), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	data, err := s.codec.encode(key, token)
 
 	if err != nil {
 		return err
 	}
 
-	if err := gob.NewEncoder(file).Encode(token); err != nil {
-
-		defer func() {
-			_ = os.Remove(file.Name())
-		}()
-
+	if err := writeFile(s.file(key), data); err != nil {
 		return err
 	}
 
-	defer file.Close()
+	if info, err := os.Stat(s.file(key)); err == nil {
+		item.info = info
+	}
 
 	return nil
 }
 
 func (s *storageFile) Get(key string) (Token, error) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
-	if item, ok := s.items[key]; ok {
-		return item, nil
-	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	file, err := os.Open(filepath.Join(s.root, key))
+	file, err := os.Open(s.file(key))
 
 	if err != nil {
 
 		if errors.Is(err, os.ErrNotExist) {
+			if item, ok := s.items[key]; ok {
+				return item.token, nil
+			}
 			return nil, nil
 		}
 
@@ -76,13 +138,89 @@ func (s *storageFile) Get(key string) (Token, error) {
 
 	defer file.Close()
 
-	var token Token = new(token)
+	info, err := file.Stat()
 
-	if err := gob.NewDecoder(file).Decode(&token); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
-	s.items[key] = token
+	// only use the copy in memory when no other process changed the file
+	if item, ok := s.items[key]; ok && item.unchanged(info) {
+		return item.token, nil
+	}
+
+	data, err := io.ReadAll(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.codec.decode(key, data)
+
+	if err != nil || nil == token {
+		return nil, err
+	}
+
+	s.items[key] = &storedToken{token: token, info: info}
 
 	return token, nil
 }
+
+func (s *storageFile) Lock(ctx context.Context, key string) (func(), error) {
+	file, err := os.OpenFile(s.file(key)+".lock", os.O_RDWR|os.O_CREATE, 0600)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		ok, err := tryLockFile(file)
+
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		if ok {
+			return func() {
+				_ = unlockFile(file)
+				_ = file.Close()
+			}, nil
+		}
+
+		if err := sleep(ctx, lockRetryInterval); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+}
+
+// writeFile writes the data to a temporary file that is renamed to the
+// given name, so readers never see a partially written file.
+func writeFile(name string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), name)
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/gob"
+	"errors"
+)
+
+// encryptedFileMagic prefixes the files so a different (format) version
+// can be detected and treated as a cache miss.
+var encryptedFileMagic = []byte("TIPTOK1\x00")
+
+// KeySecret returns a secret derived from the private key, which can be
+// used for NewEncryptedTokenFileStorage.
+func KeySecret(key *rsa.PrivateKey) []byte {
+	var sum = sha256.Sum256(x509.MarshalPKCS1PrivateKey(key))
+	return sum[:]
+}
+
+// NewEncryptedTokenFileStorage works as NewTokenFileStorage but encrypts
+// the tokens (AES-256-GCM) with a key derived from the secret. Files that
+// can't be authenticated or decrypted (corrupt, tampered or written with
+// another secret) are handled as if no token was stored.
+//
+// storage, err := NewEncryptedTokenFileStorage(filepath.Join(os.TempDir(), "transip"), KeySecret(key))
+func NewEncryptedTokenFileStorage(root string, secret []byte) (Storage, error) {
+
+	if len(secret) == 0 {
+		return nil, errors.New("missing secret for encrypted token storage")
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, nil, "transip token storage", 32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newStorageFile(root, &aeadCodec{aead: aead})
+}
+
+type aeadCodec struct {
+	aead cipher.AEAD
+}
+
+func (c *aeadCodec) extension() string {
+	return ".enc"
+}
+
+func (c *aeadCodec) encode(key string, token Token) ([]byte, error) {
+	var nonce = make([]byte, c.aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	var plain = []byte(token.String())
+
+	if x, ok := token.(gob.GobEncoder); ok {
+		data, err := x.GobEncode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		plain = data
+	}
+
+	var buf = make([]byte, 0, len(encryptedFileMagic)+len(nonce)+len(plain)+c.aead.Overhead())
+
+	buf = append(buf, encryptedFileMagic...)
+	buf = append(buf, nonce...)
+	// the storage key is used as additional data so a file can't be swapped for another
+	return c.aead.Seal(buf, nonce, plain, []byte(key)), nil
+}
+
+func (c *aeadCodec) decode(key string, data []byte) (Token, error) {
+
+	if false == bytes.HasPrefix(data, encryptedFileMagic) || len(data) < len(encryptedFileMagic)+c.aead.NonceSize() {
+		return nil, nil
+	}
+
+	data = data[len(encryptedFileMagic):]
+
+	plain, err := c.aead.Open(nil, data[:c.aead.NonceSize()], data[c.aead.NonceSize():], []byte(key))
+
+	if err != nil {
+		return nil, nil
+	}
+
+	var token = new(token)
+
+	if err := token.GobDecode(plain); err != nil {
+		return nil, nil
+	}
+
+	return token, nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEncryptedTokenFileStorage(t *testing.T) {
+	var root = t.TempDir()
+
+	storage, err := NewEncryptedTokenFileStorage(root, []byte("secret"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var x = testToken(t, 1, time.Hour)
+
+	x.(*token).skew = -2 * time.Hour
+
+	if err := storage.Set("key", x); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "key.enc"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte(x.String())) {
+		t.Fatal("expecting the token to be encrypted")
+	}
+
+	// a new storage so the token is read from disk
+	other, _ := NewEncryptedTokenFileStorage(root, []byte("secret"))
+
+	y, err := other.Get("key")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nil == y || y.String() != x.String() || false == y.ExpiresAt().Equal(x.ExpiresAt()) {
+		t.Fatalf("expecting %s (expires %s) got %v", x, x.ExpiresAt(), y)
+	}
+}
+
+func TestEncryptedTokenFileStorage_Miss(t *testing.T) {
+	var root = t.TempDir()
+	var x = testToken(t, 1, time.Hour)
+
+	storage, _ := NewEncryptedTokenFileStorage(root, []byte("secret"))
+
+	if err := storage.Set("key", x); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(root, "key.enc"))
+
+	var get = func(secret, key string, data []byte) Token {
+		var root = t.TempDir()
+
+		if err := os.WriteFile(filepath.Join(root, key+".enc"), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		storage, _ := NewEncryptedTokenFileStorage(root, []byte(secret))
+
+		token, err := storage.Get(key)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+
+	if nil == get("secret", "key", data) {
+		t.Fatal("expecting a token for a copy of the file")
+	}
+
+	var tampered = bytes.Clone(data)
+
+	tampered[len(tampered)-1] ^= 1
+
+	for name, token := range map[string]Token{
+		"OtherSecret": get("other", "key", data),
+		"OtherKey":    get("secret", "other", data),
+		"Tampered":    get("secret", "key", tampered),
+		"Truncated":   get("secret", "key", data[:len(encryptedFileMagic)+4]),
+		"Plain":       get("secret", "key", []byte(x.String())),
+		"Version":     get("secret", "key", append([]byte("TIPTOK2\x00"), data[len(encryptedFileMagic):]...)),
+	} {
+		if nil != token {
+			t.Fatalf("%s: expecting cache miss got %s", name, token)
+		}
+	}
+}
+
+func TestEncryptedTokenFileStorage_Legacy(t *testing.T) {
+	var root = t.TempDir()
+	var x = testToken(t, 1, time.Hour)
+
+	storage, _ := NewEncryptedTokenFileStorage(root, []byte("secret"))
+
+	// older releases encrypted the bare jwt
+	var aead = storage.(*storageFile).codec.(*aeadCodec).aead
+	var nonce = make([]byte, aead.NonceSize())
+	var data = aead.Seal(append(bytes.Clone(encryptedFileMagic), nonce...), nonce, []byte(x.String()), []byte("key"))
+
+	if err := os.WriteFile(filepath.Join(root, "key.enc"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if y, err := storage.Get("key"); err != nil || nil == y || y.String() != x.String() {
+		t.Fatalf("expecting %s got %v (%v)", x, y, err)
+	}
+}
+
+func TestNewEncryptedTokenFileStorage_MissingSecret(t *testing.T) {
+	if _, err := NewEncryptedTokenFileStorage(t.TempDir(), nil); err == nil {
+		t.Fatal("expecting error for missing secret")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package client
+
+import (
+	"os"
+)
+
+// tryLockFile is a noop on platforms without flock, so processes
+// can't be synchronised and each will request its own token.
+func tryLockFile(*os.File) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
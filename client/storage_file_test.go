@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testToken returns a token with a jwt that expires after given duration,
+// the id makes the jwt unique.
+func testToken(t *testing.T, id int, expires time.Duration) Token {
+	var payload = base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d,"jti":"%d"}`, time.Now().Add(expires).Unix(), id)))
+
+	token, err := NewToken("eyJhbGciOiJSUzUxMiJ9." + payload + ".c2lnbmF0dXJl")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestStorageFile(t *testing.T) {
+	var root = t.TempDir()
+
+	a, err := newStorageFile(root, gobCodec{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := newStorageFile(root, gobCodec{})
+
+	if x, err := a.Get("key"); err != nil || nil != x {
+		t.Fatalf("expecting cache miss got %v (%v)", x, err)
+	}
+
+	var first, second = testToken(t, 1, time.Hour), testToken(t, 2, time.Hour)
+
+	if err := a.Set("key", first); err != nil {
+		t.Fatal(err)
+	}
+
+	if x, err := b.Get("key"); err != nil || x.String() != first.String() {
+		t.Fatalf("expecting token of other storage got %v (%v)", x, err)
+	}
+
+	if err := b.Set("key", second); err != nil {
+		t.Fatal(err)
+	}
+
+	// a write within the resolution of the file system timestamps
+	var modified = a.items["key"].info.ModTime()
+
+	if err := os.Chtimes(filepath.Join(root, "key"), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	if x, err := a.Get("key"); err != nil || x.String() != second.String() {
+		t.Fatalf("expecting token written by other storage got %v (%v)", x, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"errors"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -13,21 +14,56 @@ import (
 type token struct {
 	value   string
 	payload *tokenPayload
+	// skew is the difference between the clock of the api and the local
+	// clock, estimated from the issue time when the token was received.
+	skew time.Duration
 }
 
+// tokenFormat prefixes stored tokens that carry more than the jwt. A token
+// without skew is stored as the bare jwt, as done by older releases, so
+// those can still read it.
+const tokenFormat = "v1\n"
+
 func (t *token) GobEncode() ([]byte, error) {
-	return []byte(t.value), nil
+
+	if t.skew == 0 {
+		return []byte(t.value), nil
+	}
+
+	return []byte(tokenFormat + strconv.FormatInt(int64(t.skew), 10) + "\n" + t.value), nil
 }
 
 func (t *token) GobDecode(data []byte) error {
-	t.value = string(data)
+	var value = string(data)
+
+	t.skew = 0
+
+	if x, ok := strings.CutPrefix(value, tokenFormat); ok {
+		skew, jwt, ok := strings.Cut(x, "\n")
+
+		if false == ok {
+			return errors.New("invalid stored token")
+		}
+
+		n, err := strconv.ParseInt(skew, 10, 64)
 
-	payload, err := getPayload(t.value)
+		if err != nil {
+			return err
+		}
+
+		t.skew = time.Duration(n)
+		value = jwt
+	} else if strings.Contains(value, "\n") {
+		return errors.New("unsupported stored token format")
+	}
+
+	payload, err := getPayload(value)
 
 	if err != nil {
 		return err
 	}
 
+	t.value = value
 	t.payload = payload
 
 	return nil
@@ -37,11 +73,34 @@ func (t *token) String() string {
 	return t.value
 }
 
+// local converts a timestamp of the api to the local clock
+func (t *token) local(x int64) time.Time {
+	return time.Unix(x, 0).Add(-t.skew)
+}
+
 func (t *token) IsExpired() bool {
+	return t.ExpiresAt().Before(time.Now())
+}
+
+func (t *token) ExpiresAt() time.Time {
 	if nil == t.payload {
-		return true
+		return time.Time{}
+	}
+	return t.local(t.payload.Expires)
+}
+
+func (t *token) NotBefore() time.Time {
+	if nil == t.payload || 0 == t.payload.NotBefore {
+		return time.Time{}
 	}
-	return time.Unix(t.payload.Expires, 0).Before(time.Now())
+	return t.local(t.payload.NotBefore)
+}
+
+func (t *token) IssuedAt() time.Time {
+	if nil == t.payload || 0 == t.payload.IssuedAt {
+		return time.Time{}
+	}
+	return t.local(t.payload.IssuedAt)
 }
 
 func (t *token) ReadOnly() bool {
@@ -61,6 +120,11 @@ func (t *token) GlobalKey() bool {
 type Token interface {
 	String() string
 	IsExpired() bool
+	// ExpiresAt, NotBefore and IssuedAt return the claims of the token
+	// converted to the local clock (so corrected for clock skew).
+	ExpiresAt() time.Time
+	NotBefore() time.Time
+	IssuedAt() time.Time
 	ReadOnly() bool
 	GlobalKey() bool
 
@@ -83,7 +147,30 @@ func NewToken(x string) (Token, error) {
 		return nil, err
 	}
 
-	return &token{value: x, payload: payload}, nil
+	var object = &token{value: x, payload: payload}
+
+	// the token was just issued so the difference with the issue
+	// time (or not before) is the skew between the clocks
+	if issued := max(payload.IssuedAt, payload.NotBefore); issued > 0 {
+		if skew := time.Unix(issued, 0).Sub(time.Now()); skew.Abs() > time.Second {
+			object.skew = skew.Truncate(time.Second)
+		}
+	}
+
+	return object, nil
+}
+
+// renewAt returns the time the token should be renewed, which is the
+// leeway before it expires but at most halfway its lifetime so short
+// lived tokens aren't renewed on every request.
+func renewAt(token Token, leeway time.Duration) time.Time {
+	var expires = token.ExpiresAt()
+
+	if issued := token.IssuedAt(); false == issued.IsZero() {
+		leeway = min(leeway, expires.Sub(issued)/2)
+	}
+
+	return expires.Add(-max(leeway, 0))
 }
 
 func getPayload(x string) (*tokenPayload, error) {
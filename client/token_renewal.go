@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTokenRenewalLeeway is the time before a token expires at which
+// a new token will be requested.
+const DefaultTokenRenewalLeeway = time.Minute
+
+// tokenRenewalRetry is the interval used to retry a failed background renewal
+const tokenRenewalRetry = 30 * time.Second
+
+// ConfigTokenRenewal can be implemented to set the leeway before the
+// expiration of a token at which a new token is requested, so requests
+// won't be denied halfway a batch. A negative value disables the leeway.
+type ConfigTokenRenewal interface {
+	GetTokenRenewalLeeway() time.Duration
+}
+
+// TokenRenewer is implemented by clients that can keep the token fresh
+// in the background, which is useful for long-running processes.
+type TokenRenewer interface {
+	// RenewTokens renews the token every time it is about to expire,
+	// until the context is done. Errors are passed to onError (when not
+	// nil) after which the renewal is retried.
+	RenewTokens(ctx context.Context, onError func(error))
+}
+
+func (a *client) RenewTokens(ctx context.Context, onError func(error)) {
+	for {
+		token, err := a.tokens.getToken(ctx)
+
+		var wait = tokenRenewalRetry
+
+		if err == nil {
+			wait = time.Until(renewAt(token, a.tokens.leeway))
+		} else if nil != onError && nil == ctx.Err() {
+			onError(err)
+		}
+
+		if err := sleep(ctx, max(wait, time.Second)); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// legacyToken is stored like tokens were before the clock skew was kept
+type legacyToken string
+
+func (t legacyToken) GobEncode() ([]byte, error) {
+	return []byte(t), nil
+}
+
+func TestToken_GobRoundTrip(t *testing.T) {
+	var x = testToken(t, 1, time.Hour).(*token)
+
+	for _, skew := range []time.Duration{0, -2 * time.Hour, 90 * time.Second} {
+		x.skew = skew
+
+		data, err := x.GobEncode()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var y = new(token)
+
+		if err := y.GobDecode(data); err != nil {
+			t.Fatalf("skew %s: %v", skew, err)
+		}
+
+		if y.String() != x.String() || y.skew != skew || false == y.ExpiresAt().Equal(x.ExpiresAt()) {
+			t.Fatalf("skew %s: expecting %s (%s) got %s (%s)", skew, x, x.skew, y, y.skew)
+		}
+	}
+}
+
+func TestToken_GobDecodeLegacy(t *testing.T) {
+	var jwt = testToken(t, 1, time.Hour).String()
+	var buf = new(bytes.Buffer)
+
+	if err := gob.NewEncoder(buf).Encode(legacyToken(jwt)); err != nil {
+		t.Fatal(err)
+	}
+
+	var root = t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(root, "key"), buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := newStorageFile(root, gobCodec{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := storage.Get("key")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nil == x || x.String() != jwt || x.(*token).skew != 0 {
+		t.Fatalf("expecting %s got %v", jwt, x)
+	}
+}
+
+func TestToken_GobDecodeInvalid(t *testing.T) {
+	var jwt = testToken(t, 1, time.Hour).String()
+
+	for _, data := range []string{
+		"",
+		"v1\n" + jwt,
+		"v1\nskew\n" + jwt,
+		"v2\n0\n" + jwt,
+		jwt + "\n3600",
+	} {
+		if err := new(token).GobDecode([]byte(data)); err == nil {
+			t.Fatalf("expecting error for %q", data)
+		}
+	}
+}
+
+func TestRenewAt(t *testing.T) {
+	var now = time.Now().Truncate(time.Second)
+
+	for _, c := range []struct {
+		lifetime time.Duration
+		leeway   time.Duration
+		expect   time.Duration
+	}{
+		{time.Hour, time.Minute, 59 * time.Minute},
+		{time.Hour, 2 * time.Hour, 30 * time.Minute},
+		{time.Hour, 0, time.Hour},
+		{time.Hour, -time.Minute, time.Hour},
+		{4 * time.Second, time.Hour, 2 * time.Second},
+	} {
+		var x = &token{payload: &tokenPayload{IssuedAt: now.Unix(), Expires: now.Add(c.lifetime).Unix()}}
+
+		if at := renewAt(x, c.leeway); false == at.Equal(now.Add(c.expect)) {
+			t.Fatalf("lifetime %s leeway %s: expecting renewal after %s got %s", c.lifetime, c.leeway, c.expect, at.Sub(now))
+		}
+	}
+
+	// without issue time the leeway isn't capped
+	var x = &token{payload: &tokenPayload{Expires: now.Add(time.Hour).Unix()}}
+
+	if at := renewAt(x, 2*time.Hour); false == at.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("expecting renewal an hour ago got %s", at.Sub(now))
+	}
+}
+
+func TestNewToken_Skew(t *testing.T) {
+
+	for _, skew := range []time.Duration{0, 2 * time.Hour, -2 * time.Hour} {
+		var payload = fmt.Sprintf(`{"iat":%d,"exp":%d}`, time.Now().Add(skew).Unix(), time.Now().Add(skew+time.Hour).Unix())
+		var jwt = "eyJhbGciOiJSUzUxMiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+
+		y, err := NewToken(jwt)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the issue time converted to the local clock is now
+		if d := time.Since(y.IssuedAt()).Abs(); d > 2*time.Second {
+			t.Fatalf("skew %s: expecting issue time close to now got %s off", skew, d)
+		}
+	}
+}
@@ -2,55 +2,161 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
+	"time"
 )
 
+// tokenRefreshTimeout limits how long a shared token refresh can take,
+// including the wait for the lock of the storage.
+const tokenRefreshTimeout = time.Minute
+
 type transport struct {
 	http.RoundTripper
 
 	config  Config
 	storage Storage
 	refresh TokenFetcher
+	limiter *rateLimiter
+	leeway  time.Duration
+
+	mutex  sync.Mutex
+	flight *tokenFlight
 }
 
+// tokenFlight is an in-flight token refresh that is shared by all
+// concurrent requests, so only one token is created.
+type tokenFlight struct {
+	done  chan struct{}
+	token Token
+	err   error
+}
+
+// getToken returns the stored token or requests a new one when it is
+// missing, expired or was rejected by the api (see "token_rejected").
 func (t *transport) getToken(ctx context.Context) (Token, error) {
+	var rejected = ContextValue(ctx, "token_rejected", "")
+
 	token, err := t.storage.Get(t.config.StorageKey())
 
 	if err != nil {
 		return nil, err
 	}
 
-	if token == nil || token.IsExpired() || ContextValue(ctx, "token_refresh_force", false) {
-		value, err := t.refresh(ctx, t.config)
+	if t.isUsable(token, rejected) {
+		return token, nil
+	}
 
-		if err != nil {
-			return nil, err
+	for {
+		t.mutex.Lock()
+
+		var flight, leader = t.flight, false
+
+		if nil == flight {
+			flight, leader = &tokenFlight{done: make(chan struct{})}, true
+			t.flight = flight
+
+			go t.fly(ctx, flight, rejected)
+		}
+
+		t.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-flight.done:
+		}
+
+		// the refresh of another request was aborted, so try again while
+		// this context is still alive
+		if false == leader && (errors.Is(flight.err, context.Canceled) || errors.Is(flight.err, context.DeadlineExceeded)) {
+			continue
+		}
+
+		if flight.err == nil && false == t.isUsable(flight.token, rejected) {
+			return nil, errors.New("refreshed token was rejected")
 		}
 
-		token, err = NewToken(value)
+		return flight.token, flight.err
+	}
+}
+
+// fly refreshes the token for the flight on a context that is detached from
+// the request that started it, so the requests waiting for the flight won't
+// fail when that request is cancelled.
+func (t *transport) fly(ctx context.Context, flight *tokenFlight, rejected string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenRefreshTimeout)
+
+	defer cancel()
+
+	flight.token, flight.err = t.refreshToken(ctx, rejected)
+
+	t.mutex.Lock()
+	t.flight = nil
+	t.mutex.Unlock()
+
+	close(flight.done)
+}
+
+func (t *transport) refreshToken(ctx context.Context, rejected string) (Token, error) {
+
+	// make sure only one process will authenticate while others wait
+	if storage, ok := t.storage.(LockingStorage); ok {
+		unlock, err := storage.Lock(ctx, t.config.StorageKey())
 
 		if err != nil {
 			return nil, err
 		}
 
-		if err := t.storage.Set(t.config.StorageKey(), token); err != nil {
-			return nil, err
+		defer unlock()
+
+		// check if the token was refreshed while waiting for the lock
+		if token, err := t.storage.Get(t.config.StorageKey()); err == nil && t.isUsable(token, rejected) {
+			return token, nil
 		}
 	}
 
+	value, err := t.refresh(ctx, t.config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := NewToken(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.storage.Set(t.config.StorageKey(), token); err != nil {
+		return nil, err
+	}
+
 	return token, nil
 }
 
+// isUsable returns true when the token was not rejected by the api and
+// doesn't expire within the leeway.
+func (t *transport) isUsable(token Token, rejected string) bool {
+	return nil != token && renewAt(token, t.leeway).After(time.Now()) && token.String() != rejected
+}
+
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
+	var jwt Token
+
 	if ContextValue(req.Context(), "authorize", true) {
-		jwt, err := t.getToken(req.Context())
+		token, err := t.getToken(req.Context())
 
 		if err != nil {
 			return nil, err
 		}
 
+		jwt = token
+
 		req.Header.Set("authorization", fmt.Sprintf("Bearer %s", jwt))
 	}
 
@@ -61,12 +167,64 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Set("content-type", "application/json")
 	req.Header.Set("accept", "application/json")
 
+	if err := t.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+
 	response, err := t.RoundTripper.RoundTrip(req)
 
-	// perhaps the token expired of revoked? let`s try once more
-	if nil != response && response.StatusCode == http.StatusUnauthorized && false == ContextValue(req.Context(), "token_refresh_force", false) {
-		return t.RoundTrip(req.WithContext(context.WithValue(req.Context(), "token_refresh_force", true)))
+	if nil != response {
+		t.limiter.update(response)
+	}
+
+	// quota exceeded, wait until reset and try again when the request can be replayed
+	if retries := ContextValue(req.Context(), "rate_limit_retries", 0); nil != response && response.StatusCode == http.StatusTooManyRequests && retries < maxRateLimitRetries {
+		if retry, ok := rewind(req); ok {
+			_ = response.Body.Close()
+
+			if err := sleep(req.Context(), t.limiter.retryAfter(response)); err != nil {
+				return nil, err
+			}
+
+			return t.RoundTrip(retry.WithContext(context.WithValue(req.Context(), "rate_limit_retries", retries+1)))
+		}
+	}
+
+	// perhaps the token expired of revoked? let`s try once more when the
+	// body can be sent again, otherwise the 401 response is returned
+	if nil != response && response.StatusCode == http.StatusUnauthorized && nil != jwt && "" == ContextValue(req.Context(), "token_rejected", "") {
+		if retry, ok := rewind(req); ok {
+			_, _ = io.Copy(io.Discard, response.Body)
+			_ = response.Body.Close()
+
+			return t.RoundTrip(retry.WithContext(context.WithValue(req.Context(), "token_rejected", jwt.String())))
+		}
 	}
 
 	return response, err
 }
+
+// rewind returns a request that can be sent again, which is only possible
+// when the request has no body or the body can be recreated.
+func rewind(req *http.Request) (*http.Request, bool) {
+
+	if nil == req.Body || http.NoBody == req.Body {
+		return req, true
+	}
+
+	if nil == req.GetBody {
+		return nil, false
+	}
+
+	body, err := req.GetBody()
+
+	if err != nil {
+		return nil, false
+	}
+
+	var clone = req.Clone(req.Context())
+
+	clone.Body = body
+
+	return clone, true
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRateLimitThreshold = 10
+	maxRateLimitRetries       = 3
+)
+
+// RateLimit represents the request quota as last reported by the api
+// through the X-Rate-Limit-* headers.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Known returns true when the api has reported the quota at least once
+func (r RateLimit) Known() bool {
+	return false == r.Reset.IsZero()
+}
+
+// RateLimitAware is implemented by clients that keep track of the request
+// quota of the account.
+type RateLimitAware interface {
+	RateLimit() RateLimit
+}
+
+type rateLimiter struct {
+	mutex     sync.Mutex
+	state     RateLimit
+	threshold int
+}
+
+func (r *rateLimiter) State() RateLimit {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.state
+}
+
+// update will set the current state based on the X-Rate-Limit-* headers
+// of the response, the reset can be either an unix timestamp or the
+// seconds until reset.
+func (r *rateLimiter) update(response *http.Response) {
+	limit, err := strconv.Atoi(response.Header.Get("x-rate-limit-limit"))
+
+	if err != nil {
+		return
+	}
+
+	remaining, err := strconv.Atoi(response.Header.Get("x-rate-limit-remaining"))
+
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(response.Header.Get("x-rate-limit-reset"), 10, 64)
+
+	if err != nil {
+		return
+	}
+
+	var state = RateLimit{Limit: limit, Remaining: remaining}
+
+	if reset > 1_000_000_000 {
+		state.Reset = time.Unix(reset, 0)
+	} else {
+		state.Reset = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.state = state
+}
+
+// delay returns the time to wait before the next request can be sent. When
+// the remaining requests drop below the threshold the requests are spread
+// over the time left until reset, and when exhausted we wait for the reset.
+func (r *rateLimiter) delay() time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if false == r.state.Known() || r.state.Remaining > r.threshold {
+		return 0
+	}
+
+	var left = time.Until(r.state.Reset)
+
+	if left <= 0 {
+		return 0
+	}
+
+	if r.state.Remaining <= 0 {
+		return left
+	}
+
+	// claim one of the remaining requests so concurrent
+	// callers will be spread out over the time left
+	r.state.Remaining--
+
+	return left / time.Duration(r.state.Remaining+2)
+}
+
+func (r *rateLimiter) wait(ctx context.Context) error {
+	return sleep(ctx, r.delay())
+}
+
+// retryAfter returns the time to wait after a 429 response, which will
+// be the Retry-After header when present or else the time until reset.
+func (r *rateLimiter) retryAfter(response *http.Response) time.Duration {
+
+	if x, ok := parseRetryAfter(response.Header.Get("retry-after")); ok {
+		return x
+	}
+
+	if state := r.State(); state.Known() {
+		return time.Until(state.Reset)
+	}
+
+	return time.Second
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+
+	if duration <= 0 {
+		return nil
+	}
+
+	var timer = time.NewTimer(duration)
+
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
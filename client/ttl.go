@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/pbergman/provider"
+)
+
+// AllowedExpires holds the expire values (in seconds) accepted by TransIP
+var AllowedExpires = []int{60, 300, 3600, 86400}
+
+// TTLPolicy defines how the ttl of new records is mapped on an expire
+// value, a ttl of zero always results in the default of 1 hour.
+type TTLPolicy uint8
+
+const (
+	// TTLDefault keeps ttl between 1 second and 1 day and uses 1 hour otherwise
+	TTLDefault TTLPolicy = iota
+	// TTLRound rounds to the nearest allowed expire value
+	TTLRound
+	// TTLClamp uses the largest allowed expire value that is not above the
+	// ttl, so records are never cached longer than requested (a ttl below
+	// the smallest value uses the smallest value)
+	TTLClamp
+	// TTLReject returns an error for ttl that are not an allowed expire value
+	TTLReject
+)
+
+func (t *TTLPolicy) UnmarshalJSON(b []byte) error {
+	var x int
+
+	if err := json.Unmarshal(b, &x); err == nil {
+		*t = TTLPolicy(x)
+		return nil
+	}
+
+	var z string
+
+	if err := json.Unmarshal(b, &z); err != nil {
+		return errors.New("invalid ttl policy")
+	}
+
+	switch strings.ToLower(z) {
+	case "default", "":
+		*t = TTLDefault
+	case "round":
+		*t = TTLRound
+	case "clamp":
+		*t = TTLClamp
+	case "reject":
+		*t = TTLReject
+	default:
+		return fmt.Errorf("invalid ttl policy \"%s\"", z)
+	}
+
+	return nil
+}
+
+// Expire returns the expire in seconds for given ttl according to the policy
+func (t TTLPolicy) Expire(ttl time.Duration) (int, error) {
+	var seconds = int(ttl / time.Second)
+
+	if ttl <= 0 {
+		return 3600, nil
+	}
+
+	switch t {
+	case TTLRound:
+		var expire = AllowedExpires[0]
+
+		for _, x := range AllowedExpires {
+			if abs(x-seconds) < abs(expire-seconds) {
+				expire = x
+			}
+		}
+
+		return expire, nil
+	case TTLClamp:
+		var expire = slices.Min(AllowedExpires)
+
+		for _, x := range AllowedExpires {
+			if x <= seconds && x > expire {
+				expire = x
+			}
+		}
+
+		return expire, nil
+	case TTLReject:
+		if false == slices.Contains(AllowedExpires, seconds) || ttl%time.Second != 0 {
+			return 0, fmt.Errorf("%w: ttl %s is not one of the allowed expire values %v", ErrInvalidRecord, ttl, AllowedExpires)
+		}
+
+		return seconds, nil
+	default:
+		if seconds > 0 && seconds <= 86400 {
+			return seconds, nil
+		}
+
+		return 3600, nil
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// ConfigTTLPolicy can be implemented to set the policy used for the ttl of
+// new records, and to get notified about records which ttl was adjusted.
+type ConfigTTLPolicy interface {
+	GetTTLPolicy() TTLPolicy
+	TTLAdjusted(domain string, record libdns.RR, ttl time.Duration)
+}
+
+// applyTTLPolicy returns a change list where the records marked for
+// creation have the ttl of the configured policy.
+func (c *client) applyTTLPolicy(domain string, change provider.ChangeList) (provider.ChangeList, error) {
+	var policy = TTLDefault
+	var config, ok = c.config.(ConfigTTLPolicy)
+
+	if ok {
+		policy = config.GetTTLPolicy()
+	}
+
+	var list = &ttlChangeList{ChangeList: change, records: make(map[*libdns.RR]*libdns.RR)}
+
+	for record := range change.Iterate(provider.Create) {
+		expire, err := policy.Expire(record.TTL)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s %s %s: %w", record.Name, record.Type, record.Data, err)
+		}
+
+		if ttl := time.Duration(expire) * time.Second; ttl != record.TTL {
+			var x = *record
+
+			x.TTL = ttl
+
+			list.records[record] = &x
+
+			if ok && record.TTL > 0 {
+				config.TTLAdjusted(domain, *record, ttl)
+			}
+		}
+	}
+
+	return list, nil
+}
+
+type ttlChangeList struct {
+	provider.ChangeList
+	records map[*libdns.RR]*libdns.RR
+}
+
+func (t *ttlChangeList) Iterate(state provider.ChangeState) iter.Seq[*libdns.RR] {
+	return func(yield func(*libdns.RR) bool) {
+		for record := range t.ChangeList.Iterate(state) {
+
+			if x, ok := t.records[record]; ok {
+				record = x
+			}
+
+			if false == yield(record) {
+				return
+			}
+		}
+	}
+}
+
+func (t *ttlChangeList) Creates() []*libdns.RR {
+	return slices.Collect(t.Iterate(provider.Create))
+}
+
+func (t *ttlChangeList) GetList() []*libdns.RR {
+	return slices.Collect(t.Iterate(provider.Create | provider.NoChange))
+}
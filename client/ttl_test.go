@@ -0,0 +1,86 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTTLPolicy_Expire(t *testing.T) {
+	for _, x := range []struct {
+		policy TTLPolicy
+		ttl    time.Duration
+		expect int
+	}{
+		{TTLDefault, 0, 3600},
+		{TTLDefault, -time.Second, 3600},
+		{TTLDefault, 90 * time.Second, 90},
+		{TTLDefault, 24 * time.Hour, 86400},
+		{TTLDefault, 25 * time.Hour, 3600},
+		{TTLDefault, 500 * time.Millisecond, 3600},
+		{TTLRound, 0, 3600},
+		{TTLRound, time.Second, 60},
+		{TTLRound, 170 * time.Second, 60},
+		{TTLRound, 190 * time.Second, 300},
+		{TTLRound, 2 * time.Hour, 3600},
+		{TTLRound, 72 * time.Hour, 86400},
+		{TTLClamp, 0, 3600},
+		{TTLClamp, time.Second, 60},
+		{TTLClamp, 299 * time.Second, 60},
+		{TTLClamp, 300 * time.Second, 300},
+		{TTLClamp, 23 * time.Hour, 3600},
+		{TTLClamp, 72 * time.Hour, 86400},
+		{TTLReject, 0, 3600},
+		{TTLReject, 5 * time.Minute, 300},
+		{TTLReject, 24 * time.Hour, 86400},
+	} {
+		expire, err := x.policy.Expire(x.ttl)
+
+		if err != nil {
+			t.Fatalf("policy %d ttl %s: %v", x.policy, x.ttl, err)
+		}
+
+		if expire != x.expect {
+			t.Fatalf("policy %d ttl %s: expecting %d got %d", x.policy, x.ttl, x.expect, expire)
+		}
+	}
+}
+
+func TestTTLPolicy_ExpireReject(t *testing.T) {
+	for _, ttl := range []time.Duration{time.Second, 301 * time.Second, 300*time.Second + time.Millisecond, 48 * time.Hour} {
+		if _, err := TTLReject.Expire(ttl); false == errors.Is(err, ErrInvalidRecord) {
+			t.Fatalf("ttl %s: expecting ErrInvalidRecord got %v", ttl, err)
+		}
+	}
+}
+
+func TestTTLPolicy_UnmarshalJSON(t *testing.T) {
+	for data, expect := range map[string]TTLPolicy{
+		`0`:         TTLDefault,
+		`2`:         TTLClamp,
+		`""`:        TTLDefault,
+		`"default"`: TTLDefault,
+		`"Round"`:   TTLRound,
+		`"clamp"`:   TTLClamp,
+		`"REJECT"`:  TTLReject,
+	} {
+		var policy TTLPolicy
+
+		if err := json.Unmarshal([]byte(data), &policy); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+
+		if policy != expect {
+			t.Fatalf("%s: expecting %d got %d", data, expect, policy)
+		}
+	}
+
+	for _, data := range []string{`"floor"`, `true`, `{}`} {
+		var policy TTLPolicy
+
+		if err := json.Unmarshal([]byte(data), &policy); err == nil {
+			t.Fatalf("%s: expecting error", data)
+		}
+	}
+}
@@ -2,10 +2,16 @@ package transip
 
 import (
 	"context"
+	"crypto"
+	"fmt"
 	"io"
+	"iter"
+	"maps"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/libdns/libdns"
 	"github.com/libdns/transip/client"
@@ -15,6 +21,12 @@ import (
 type Client interface {
 	provider.Client
 	provider.ZoneAwareClient
+	client.DomainIterator
+	client.RateLimitAware
+	client.DNSSecClient
+	client.NameserverClient
+	client.TokenRenewer
+	client.PermissionChecker
 }
 
 type Provider struct {
@@ -26,15 +38,46 @@ type Provider struct {
 	// restricted to clients with IP addresses included in the whitelist.
 	AuthNotGlobalKey bool `json:"not_global_key"`
 	// AuthExpirationTime specifies the time-to-live for an authentication token.
+	// Use client.NewExpirationTime for other durations than the predefined
+	// values, in json a duration ("36h") can be used as well.
 	AuthExpirationTime client.ExpirationTime `json:"expiration_time"`
+	// AuthLabel is the template for the label of new tokens, so it can be
+	// seen in the control panel which host created which token. It can hold
+	// the {hostname}, {process}, {pid}, {purpose} and {random} placeholders
+	// (see client.FormatLabel), defaults to client.DefaultLabelTemplate.
+	AuthLabel string `json:"label"`
+	// AuthLabelPurpose is the value for the {purpose} placeholder
+	AuthLabelPurpose string `json:"label_purpose"`
+	// NonceGenerator can be set to generate the nonce of authentication
+	// requests, which is useful for deterministic tests. Every nonce must
+	// be unique, an empty value will use a random nonce.
+	NonceGenerator func() string `json:"-"`
 
 	// PrivateKey can be generated here:
 	// https://www.transip.nl/cp/account/api
 	//
 	// It is used for authentication and can be provided either as a
-	// string containing the key or as a filepath to a file containing
-	// the private key.
+	// string containing the key (PEM or base64 encoded PEM) or as a
+	// filepath to a file containing the private key. Supported are rsa
+	// keys of at least 2048 bits in the PKCS #1, PKCS #8 and encrypted
+	// PKCS #8 format.
 	PrivateKey string `json:"private_key"`
+	// PrivateKeyPassphrase is used to decrypt an encrypted private key
+	PrivateKeyPassphrase string `json:"private_key_passphrase"`
+	// PrivateKeys can be set (instead of PrivateKey) with multiple keys in
+	// the same formats, to rotate keys without downtime. The keys are tried
+	// in order until one is accepted, which is used from then on. Keys that
+	// can't be loaded or are rejected by TransIP are reported with
+	// OnKeyRejected and RejectedKeys, so they can be cleaned up.
+	PrivateKeys   []string                   `json:"private_keys"`
+	OnKeyRejected func(index int, err error) `json:"-"`
+	activeKey     *int
+	rejectedKeys  map[int]error
+	// Signer can be set to sign the authentication requests with an
+	// external signer (PKCS #11, agent, KMS etc.) instead of the PrivateKey.
+	// The public key of the signer must be an rsa key. Tokens stored on disk
+	// can only be encrypted with a TokenSecret when no PrivateKey is set.
+	Signer crypto.Signer `json:"-"`
 
 	// DebugLevel sets the verbosity for logging API requests and responses.
 	DebugLevel provider.OutputLevel `json:"debug_level"`
@@ -52,20 +95,86 @@ type Provider struct {
 	// - a file path for storing keys on disk
 	// - empty, in which case keys will be stored in a "transip" directory
 	//   in the user's temp folder.
+	// Processes that share a directory use file locks so only one of them
+	// will request a new token while the others wait and reuse it.
 	TokenStorage string `json:"token_storage"`
 	tokenStorage client.Storage
 
+	// TokenRenewalLeeway is the time before a token expires at which a new
+	// token is requested, so it won't expire halfway a batch of requests.
+	// Defaults to client.DefaultTokenRenewalLeeway, a negative value will
+	// use the token until it expires. In json a duration ("2m") can be used.
+	TokenRenewalLeeway time.Duration `json:"token_renewal_leeway"`
+
+	// TokenEncryption encrypts the tokens stored on disk with a key derived
+	// from the PrivateKey, or from TokenSecret when set. Files that can't be
+	// decrypted are ignored and a new token will be requested. Requests fail
+	// when there is no TokenSecret and the PrivateKey can't be loaded.
+	TokenEncryption bool `json:"token_encryption"`
+	// TokenSecret is used (instead of the private key) as secret for the
+	// encryption of stored tokens and implies TokenEncryption.
+	TokenSecret string `json:"token_secret"`
+
 	// ClientControl has two modes:
 	// - RecordLevelControl (default): updates records individually.
 	// - FullZoneControl: replaces the entire zone in a single call.
 	//   While this is much faster, it can encounter race conditions if
 	//   another program modifies the zone simultaneously, as updates
-	//   may be overwritten.
+	//   may be overwritten (see ZoneConflictPolicy).
 	ClientControl client.ControleMode `json:"client_control_mode"`
 	client        Client
 
+	// ZoneConflictPolicy protects FullZoneControl against overwriting
+	// changes made by someone else, by checking the zone right before
+	// it is replaced:
+	// - client.ConflictOverwrite (default): no check is done.
+	// - client.ConflictAbort: returns a client.ErrZoneModified error.
+	// - client.ConflictRebase: applies the changes on top of the modified
+	//   zone and retries at most ZoneConflictRetries times
+	//   (default client.DefaultConflictRetries).
+	ZoneConflictPolicy  client.ConflictPolicy `json:"zone_conflict_policy"`
+	ZoneConflictRetries int                   `json:"zone_conflict_retries"`
+
+	// ZonesPageSize sets the number of domains requested per page when
+	// listing zones. Defaults to client.DefaultDomainsPageSize.
+	ZonesPageSize int `json:"zones_page_size"`
+	// ZonesLimit caps the number of zones returned by ListZones and
+	// IterateZones, zero (default) means no limit.
+	ZonesLimit int `json:"zones_limit"`
+
+	// TTLPolicy defines how the ttl of new records is mapped on the expire
+	// values accepted by TransIP (see client.AllowedExpires):
+	// - client.TTLDefault: ttl outside 1 second and 1 day will be 1 hour.
+	// - client.TTLRound: rounds to the nearest allowed value.
+	// - client.TTLClamp: uses the largest allowed value not above the ttl.
+	// - client.TTLReject: returns an error for ttl that are not allowed.
+	TTLPolicy client.TTLPolicy `json:"ttl_policy"`
+	// OnTTLAdjusted is called for every record of which the ttl was changed
+	// by the TTLPolicy, with the ttl that will be used.
+	OnTTLAdjusted func(zone string, record libdns.RR, ttl time.Duration) `json:"-"`
+
+	// RateLimitThreshold is the number of remaining requests (as reported by
+	// the X-Rate-Limit-Remaining header) at which requests are throttled so
+	// the quota is not exhausted before it resets. Defaults to
+	// client.DefaultRateLimitThreshold, a negative value disables throttling.
+	RateLimitThreshold int `json:"rate_limit_threshold"`
+
+	// RetryAttempts is the maximum number of times a request is sent when
+	// it fails because of a network error, a server error (5xx) or because
+	// the domain is being modified by another process (409). Only requests
+	// that are safe to repeat are retried. Defaults to
+	// client.DefaultRetryAttempts, 1 or a negative value disables retries.
+	RetryAttempts int `json:"retry_attempts"`
+	// RetryBackoff and RetryMaxBackoff are the initial and maximum wait
+	// between attempts, which doubles every attempt (with jitter) unless
+	// the api sends a longer Retry-After. In json durations ("500ms") can
+	// be used.
+	RetryBackoff    time.Duration `json:"retry_backoff"`
+	RetryMaxBackoff time.Duration `json:"retry_max_backoff"`
+
 	pLock sync.RWMutex
 	cLock sync.Mutex
+	kLock sync.Mutex
 }
 
 func (p *Provider) getClient() Client {
@@ -91,7 +200,7 @@ func (p *Provider) getClient() Client {
 		}
 
 		if p.tokenStorage == nil {
-			p.tokenStorage = NewTokenStorage(p.TokenStorage)
+			p.tokenStorage = p.newTokenStorage()
 		}
 
 		p.client = client.NewClient(p, p.tokenStorage, p.ClientControl)
@@ -105,14 +214,29 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 }
 
 func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+
+	if err := p.guardWrite(ctx); err != nil {
+		return nil, err
+	}
+
 	return provider.AppendRecords(ctx, &p.pLock, p.getClient(), zone, recs)
 }
 
 func (p *Provider) SetRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+
+	if err := p.guardWrite(ctx); err != nil {
+		return nil, err
+	}
+
 	return provider.SetRecords(ctx, &p.pLock, p.getClient(), zone, recs)
 }
 
 func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+
+	if err := p.guardWrite(ctx); err != nil {
+		return nil, err
+	}
+
 	return provider.DeleteRecords(ctx, &p.pLock, p.getClient(), zone, recs)
 }
 
@@ -120,6 +244,145 @@ func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
 	return provider.ListZones(ctx, &p.pLock, p.getClient())
 }
 
+// RenewTokens keeps the token fresh in the background (until the context
+// is done) so long-running processes never have to wait for a new token.
+// Errors are passed to onError when not nil and the renewal is retried.
+func (p *Provider) RenewTokens(ctx context.Context, onError func(error)) {
+	go p.getClient().RenewTokens(ctx, onError)
+}
+
+// CheckPermissions authenticates and reports whether the key can read and
+// write, is a global key and whether this host is on the whitelist.
+func (p *Provider) CheckPermissions(ctx context.Context) (*client.Permissions, error) {
+	return p.getClient().CheckPermissions(ctx)
+}
+
+// Writable returns an error that wraps client.ErrReadOnly when AuthReadOnly
+// is set or the stored token is read-only.
+func (p *Provider) Writable() error {
+	return p.getClient().Writable()
+}
+
+// guardWrite refuses to change records with a read-only key before the
+// current records are fetched, so nothing is (partially) applied.
+func (p *Provider) guardWrite(ctx context.Context) error {
+
+	if nil != client.DryRun(ctx) {
+		return nil
+	}
+
+	return p.Writable()
+}
+
+// ActiveKey returns the index of the PrivateKeys that was accepted last
+// and false when no key was accepted (yet).
+func (p *Provider) ActiveKey() (int, bool) {
+	p.kLock.Lock()
+	defer p.kLock.Unlock()
+
+	if nil == p.activeKey {
+		return 0, false
+	}
+
+	return *p.activeKey, true
+}
+
+// RejectedKeys returns the errors by index of the PrivateKeys that could
+// not be loaded or were rejected since they were last accepted.
+func (p *Provider) RejectedKeys() map[int]error {
+	p.kLock.Lock()
+	defer p.kLock.Unlock()
+
+	return maps.Clone(p.rejectedKeys)
+}
+
+// RateLimit returns the request quota as last reported by the api
+func (p *Provider) RateLimit() client.RateLimit {
+	return p.getClient().RateLimit()
+}
+
+// IterateZones streams the zones page by page, which can be used for
+// accounts that hold too many domains to load at once.
+func (p *Provider) IterateZones(ctx context.Context) iter.Seq2[libdns.Zone, error] {
+	return func(yield func(libdns.Zone, error) bool) {
+		for domain, err := range p.getClient().IterateDomains(ctx) {
+
+			if err != nil {
+				yield(libdns.Zone{}, err)
+				return
+			}
+
+			var name = domain.Name()
+
+			if false == strings.HasSuffix(name, ".") {
+				name += "."
+			}
+
+			if false == yield(libdns.Zone{Name: name}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (p *Provider) newTokenStorage() client.Storage {
+
+	if p.TokenStorage == "memory" || (false == p.TokenEncryption && "" == p.TokenSecret) {
+		return NewTokenStorage(p.TokenStorage)
+	}
+
+	var secret = []byte(p.TokenSecret)
+
+	if len(secret) == 0 {
+		key, err := p.GetPrivateKey()
+
+		if err != nil && len(p.PrivateKeys) > 0 {
+			key, err = p.loadPrivateKey(p.PrivateKeys[0])
+		}
+
+		// don't fall back on another storage, so the misconfiguration
+		// is reported by every request instead of going unnoticed
+		if err != nil {
+			return &invalidStorage{err: fmt.Errorf("token encryption requires a TokenSecret or a private key that can be loaded: %w", err)}
+		}
+
+		secret = client.KeySecret(key)
+	}
+
+	return NewEncryptedTokenStorage(p.TokenStorage, secret)
+}
+
+// invalidStorage is used when the token storage can't be created and
+// returns the reason for every token that is requested or stored.
+type invalidStorage struct {
+	err error
+}
+
+func (s *invalidStorage) Set(string, client.Token) error {
+	return s.err
+}
+
+func (s *invalidStorage) Get(string) (client.Token, error) {
+	return nil, s.err
+}
+
+// NewEncryptedTokenStorage works as NewTokenStorage but encrypts the tokens
+// that are stored on disk with given secret.
+func NewEncryptedTokenStorage(location string, secret []byte) client.Storage {
+
+	if location == "" {
+		location = filepath.Join(os.TempDir(), "transip")
+	}
+
+	storage, err := client.NewEncryptedTokenFileStorage(location, secret)
+
+	if err != nil {
+		return client.NewTokenMemoryStorage()
+	}
+
+	return storage
+}
+
 func NewTokenStorage(location string) client.Storage {
 	var storage client.Storage
 
@@ -0,0 +1,26 @@
+package transip
+
+import (
+	"context"
+
+	"github.com/libdns/transip/client"
+)
+
+// GetDNSSEC returns the DNSSEC key material (key tag, flags, algorithm and
+// public key) of the given zone.
+func (p *Provider) GetDNSSEC(ctx context.Context, zone string) ([]*client.DNSSecEntry, error) {
+	p.pLock.RLock()
+	defer p.pLock.RUnlock()
+
+	return p.getClient().GetDNSSEC(ctx, zone)
+}
+
+// SetDNSSEC replaces the DNSSEC key material of the given zone, this will
+// enable DNSSEC for zones that don't have any entries yet. All entries are
+// validated before anything is sent to the api.
+func (p *Provider) SetDNSSEC(ctx context.Context, zone string, entries []*client.DNSSecEntry) error {
+	p.pLock.Lock()
+	defer p.pLock.Unlock()
+
+	return p.getClient().SetDNSSEC(ctx, zone, entries)
+}
@@ -0,0 +1,56 @@
+package transip
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// duration is a time.Duration that can be unmarshalled from a duration
+// string ("90s", "1m") as well as from a number of nanoseconds.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var x int64
+
+	if err := json.Unmarshal(data, &x); err == nil {
+		*d = duration(x)
+		return nil
+	}
+
+	var z string
+
+	if err := json.Unmarshal(data, &z); err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+
+	value, err := time.ParseDuration(z)
+
+	if err != nil {
+		return err
+	}
+
+	*d = duration(value)
+
+	return nil
+}
+
+// UnmarshalJSON decodes the provider, accepting duration strings for the
+// time.Duration fields so they are consistent with the expiration_time.
+func (p *Provider) UnmarshalJSON(data []byte) error {
+	type plain Provider
+
+	var config = struct {
+		*plain
+		TokenRenewalLeeway *duration `json:"token_renewal_leeway"`
+		RetryBackoff       *duration `json:"retry_backoff"`
+		RetryMaxBackoff    *duration `json:"retry_max_backoff"`
+	}{
+		plain:              (*plain)(p),
+		TokenRenewalLeeway: (*duration)(&p.TokenRenewalLeeway),
+		RetryBackoff:       (*duration)(&p.RetryBackoff),
+		RetryMaxBackoff:    (*duration)(&p.RetryMaxBackoff),
+	}
+
+	return json.Unmarshal(data, &config)
+}
@@ -0,0 +1,25 @@
+package transip
+
+import (
+	"context"
+
+	"github.com/libdns/transip/client"
+)
+
+// GetNameservers returns the nameservers of the given zone
+func (p *Provider) GetNameservers(ctx context.Context, zone string) ([]*client.Nameserver, error) {
+	p.pLock.RLock()
+	defer p.pLock.RUnlock()
+
+	return p.getClient().GetNameservers(ctx, zone)
+}
+
+// SetNameservers replaces the nameservers of the given zone. Glue records
+// (ipv4/ipv6) must be supplied for hostnames inside the zone and only for
+// those. Use client.DefaultNameservers() to switch back to TransIP.
+func (p *Provider) SetNameservers(ctx context.Context, zone string, nameservers []*client.Nameserver) error {
+	p.pLock.Lock()
+	defer p.pLock.Unlock()
+
+	return p.getClient().SetNameservers(ctx, zone, nameservers)
+}
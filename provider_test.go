@@ -1,12 +1,35 @@
 package transip
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/libdns/libdns"
 	"github.com/libdns/transip/client"
+	"github.com/libdns/transip/transiptest"
 	"github.com/pbergman/provider"
 	"github.com/pbergman/provider/test"
 )
@@ -14,7 +37,11 @@ import (
 func TestProvider_Unmarshall(t *testing.T) {
 	var provider *Provider
 	var buf = `{
-"client_control_mode": "full zone"
+"client_control_mode": "full zone",
+"login": "user",
+"token_renewal_leeway": "2m",
+"retry_backoff": 250000000,
+"retry_max_backoff": "5s"
 }`
 
 	if err := json.Unmarshal([]byte(buf), &provider); err != nil {
@@ -25,10 +52,65 @@ func TestProvider_Unmarshall(t *testing.T) {
 		t.Fatalf("invalid client control mode, expecting %d got %d", client.FullZoneControl, provider.ClientControl)
 	}
 
+	if provider.AuthLogin != "user" || provider.TokenRenewalLeeway != 2*time.Minute || provider.RetryBackoff != 250*time.Millisecond || provider.RetryMaxBackoff != 5*time.Second {
+		t.Fatalf("unexpected config %s %s %s %s", provider.AuthLogin, provider.TokenRenewalLeeway, provider.RetryBackoff, provider.RetryMaxBackoff)
+	}
+
+	if err := json.Unmarshal([]byte(`{"retry_backoff": "soon"}`), &provider); err == nil {
+		t.Fatal("expecting error for invalid duration")
+	}
+
+}
+
+var testKey = sync.OnceValues(func() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, 2048)
+})
+
+// newTestProvider starts a transiptest server with the zone "example.com"
+// and returns a provider configured to use that server.
+func newTestProvider(t *testing.T, mode client.ControleMode) (*Provider, *transiptest.Server) {
+	key, err := testKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server = transiptest.NewServer("user", &key.PublicKey)
+
+	t.Cleanup(server.Close)
+
+	server.AddZone("example.com")
+
+	return &Provider{
+		AuthLogin:     "user",
+		PrivateKey:    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		BaseUri:       (*ApiBaseUri)(server.BaseUri()),
+		TokenStorage:  "memory",
+		ClientControl: mode,
+	}, server
+}
+
+func TestProvider_Offline(t *testing.T) {
+	for name, mode := range map[string]client.ControleMode{"RecordLevelControl": client.RecordLevelControl, "FullZoneControl": client.FullZoneControl} {
+		t.Run(name, func(t *testing.T) {
+			handler, _ := newTestProvider(t, mode)
+			test.RunProviderTests(t, handler, test.TestAll)
+		})
+	}
 }
 
 func TestProvider(t *testing.T) {
 
+	if _, ok := os.LookupEnv("LOGIN"); false == ok {
+		t.Skip("LOGIN and KEY environment variables not set")
+	}
+
 	var handler = &Provider{
 		AuthLogin:  os.Getenv("LOGIN"),
 		PrivateKey: os.Getenv("KEY"),
@@ -46,3 +128,1328 @@ func TestProvider(t *testing.T) {
 
 	test.RunProviderTests(t, handler, test.TestAll)
 }
+
+func TestProvider_ListZonesPaginated(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	for i := 0; i < 24; i++ {
+		server.AddZone(fmt.Sprintf("example-%02d.com", i))
+	}
+
+	handler.ZonesPageSize = 10
+
+	zones, err := handler.ListZones(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(zones) != 25 {
+		t.Fatalf("expecting 25 zones got %d", len(zones))
+	}
+
+	handler.ZonesLimit = 15
+
+	var count = 0
+
+	for zone, err := range handler.IterateZones(context.Background()) {
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if zone.Name[len(zone.Name)-1] != '.' {
+			t.Fatalf("missing trailing dot: %s", zone.Name)
+		}
+
+		count++
+	}
+
+	if count != 15 {
+		t.Fatalf("expecting 15 zones got %d", count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	if _, err := handler.ListZones(ctx); false == errors.Is(err, context.Canceled) {
+		t.Fatalf("expecting context canceled error got %v", err)
+	}
+}
+
+func TestProvider_RateLimit(t *testing.T) {
+	for name, threshold := range map[string]int{"throttled": 0, "unthrottled": -1} {
+		t.Run(name, func(t *testing.T) {
+			handler, server := newTestProvider(t, client.RecordLevelControl)
+			handler.RateLimitThreshold = threshold
+
+			server.SetRateLimit(3, time.Second)
+
+			for i := 0; i < 5; i++ {
+				if _, err := handler.ListZones(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var exceeded = 0
+
+			for _, request := range server.Requests() {
+				if request.Status == http.StatusTooManyRequests {
+					exceeded++
+				}
+			}
+
+			if threshold < 0 && exceeded == 0 {
+				t.Fatal("expecting requests to exceed the rate limit")
+			}
+
+			if threshold >= 0 && exceeded > 0 {
+				t.Fatalf("expecting no requests to exceed the rate limit got %d", exceeded)
+			}
+
+			if state := handler.RateLimit(); false == state.Known() || state.Limit != 3 {
+				t.Fatalf("unexpected rate limit state %+v", state)
+			}
+		})
+	}
+}
+
+func TestProvider_Errors(t *testing.T) {
+	handler, _ := newTestProvider(t, client.RecordLevelControl)
+
+	var response client.ErrorResponse
+
+	if _, err := handler.GetRecords(context.Background(), "example.org."); false == errors.Is(err, client.ErrNotFound) || false == errors.As(err, &response) {
+		t.Fatalf("expecting not found error got %v", err)
+	}
+
+	if response.Code != http.StatusNotFound || response.Method != http.MethodGet || response.Path != "/v6/domains/example.org/dns" {
+		t.Fatalf("unexpected error response %+v", response)
+	}
+
+	_, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "::1", TTL: time.Hour},
+	})
+
+	if false == errors.Is(err, client.ErrInvalidRecord) || false == errors.As(err, &response) || nil == response.Record || response.Record.Content != "::1" {
+		t.Fatalf("expecting invalid record error got %v", err)
+	}
+
+	handler, _ = newTestProvider(t, client.RecordLevelControl)
+	handler.AuthReadOnly = true
+
+	if _, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{libdns.TXT{Name: "www", Text: "foo"}}); false == errors.Is(err, client.ErrReadOnly) {
+		t.Fatalf("expecting read-only error got %v", err)
+	}
+}
+
+func TestProvider_DNSSEC(t *testing.T) {
+	handler, _ := newTestProvider(t, client.RecordLevelControl)
+
+	var entries = []*client.DNSSecEntry{
+		{KeyTag: 12345, Flags: client.DNSSecFlagsKSK, Algorithm: 13, PublicKey: "mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ=="},
+	}
+
+	if err := handler.SetDNSSEC(context.Background(), "example.com.", entries); err != nil {
+		t.Fatal(err)
+	}
+
+	curr, err := handler.GetDNSSEC(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(curr) != 1 || *curr[0] != *entries[0] {
+		t.Fatalf("unexpected dnssec entries %+v", curr)
+	}
+
+	for _, invalid := range []*client.DNSSecEntry{
+		{KeyTag: 1, Flags: 1, Algorithm: 13, PublicKey: entries[0].PublicKey},
+		{KeyTag: 1, Flags: client.DNSSecFlagsZSK, Algorithm: 9, PublicKey: entries[0].PublicKey},
+		{KeyTag: 1, Flags: client.DNSSecFlagsZSK, Algorithm: 13, PublicKey: "not base64!"},
+	} {
+		if err := handler.SetDNSSEC(context.Background(), "example.com.", []*client.DNSSecEntry{invalid}); false == errors.Is(err, client.ErrInvalidRecord) {
+			t.Fatalf("expecting invalid record error for %+v got %v", invalid, err)
+		}
+	}
+}
+
+func TestProvider_Nameservers(t *testing.T) {
+	handler, _ := newTestProvider(t, client.RecordLevelControl)
+
+	var nameservers = []*client.Nameserver{
+		{Hostname: "ns1.example.com", IPv4: "192.0.2.1", IPv6: "2001:db8::1"},
+		{Hostname: "ns2.example.net"},
+	}
+
+	if err := handler.SetNameservers(context.Background(), "example.com.", nameservers); err != nil {
+		t.Fatal(err)
+	}
+
+	curr, err := handler.GetNameservers(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(curr) != 2 || *curr[0] != *nameservers[0] || *curr[1] != *nameservers[1] {
+		t.Fatalf("unexpected nameservers %+v", curr)
+	}
+
+	for _, invalid := range []*client.Nameserver{
+		{Hostname: "ns1.example.com"},
+		{Hostname: "ns1.example.net", IPv4: "192.0.2.1"},
+		{Hostname: "ns1.example.com", IPv4: "2001:db8::1"},
+	} {
+		if err := handler.SetNameservers(context.Background(), "example.com.", []*client.Nameserver{invalid}); false == errors.Is(err, client.ErrInvalidRecord) {
+			t.Fatalf("expecting invalid record error for %+v got %v", invalid, err)
+		}
+	}
+}
+
+func TestProvider_DryRun(t *testing.T) {
+	for mode, methods := range map[client.ControleMode][]string{
+		client.RecordLevelControl: {http.MethodPatch},
+		client.FullZoneControl:    {http.MethodPut},
+	} {
+		handler, server := newTestProvider(t, mode)
+
+		server.AddZone("example.com", &client.DNSRecord{Name: "www", Type: "A", Content: "192.0.2.1", Expire: 3600})
+
+		ctx, plan := client.WithDryRun(context.Background())
+
+		out, err := handler.SetRecords(ctx, "example.com.", []libdns.Record{
+			libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.2"), TTL: time.Hour},
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(out) != 1 || out[0].RR().Data != "192.0.2.2" {
+			t.Fatalf("unexpected records returned %+v", out)
+		}
+
+		if records := server.Records("example.com"); len(records) != 1 || records[0].Content != "192.0.2.1" {
+			t.Fatalf("zone should not have been changed %+v", records)
+		}
+
+		if len(plan.Operations) != len(methods) {
+			t.Fatalf("expecting %d operations got:\n%s", len(methods), plan)
+		}
+
+		for i, method := range methods {
+			if plan.Operations[i].Method != method || plan.Operations[i].Path != "domains/example.com/dns" || len(plan.Operations[i].Body) == 0 {
+				t.Fatalf("unexpected operation %s", plan.Operations[i])
+			}
+		}
+	}
+}
+
+func TestProvider_ZoneConflictPolicy(t *testing.T) {
+	for _, policy := range []client.ConflictPolicy{client.ConflictAbort, client.ConflictRebase} {
+		handler, server := newTestProvider(t, client.FullZoneControl)
+		handler.ZoneConflictPolicy = policy
+
+		var concurrent = &client.DNSRecord{Name: "concurrent", Type: "TXT", Content: "foo", Expire: 3600}
+		var once sync.Once
+
+		// modify the zone right after the records were fetched by the provider
+		server.OnRequest(func(request *transiptest.Request) {
+			if request.Method == http.MethodGet && request.Path == "/v6/domains/example.com/dns" {
+				once.Do(func() {
+					server.AddZone("example.com", append(server.Records("example.com"), concurrent)...)
+				})
+			}
+		})
+
+		_, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{
+			libdns.TXT{Name: "www", Text: "bar", TTL: time.Hour},
+		})
+
+		var records = server.Records("example.com")
+
+		if policy == client.ConflictAbort {
+			if false == errors.Is(err, client.ErrZoneModified) || false == errors.Is(err, client.ErrConflict) {
+				t.Fatalf("expecting zone modified error got %v", err)
+			}
+
+			if len(records) != 1 || *records[0] != *concurrent {
+				t.Fatalf("zone should not have been replaced %+v", records)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(records) != 2 || *records[0] != *concurrent || records[1].Content != "bar" {
+			t.Fatalf("expecting changes rebased on modified zone got %+v", records)
+		}
+	}
+}
+
+func TestProvider_ZoneConflictPolicyNormalized(t *testing.T) {
+	for _, policy := range []client.ConflictPolicy{client.ConflictAbort, client.ConflictRebase} {
+		for _, concurrent := range []bool{false, true} {
+			handler, server := newTestProvider(t, client.FullZoneControl)
+			handler.ZoneConflictPolicy = policy
+
+			var extra = &client.DNSRecord{Name: "concurrent", Type: "TXT", Content: "foo", Expire: 3600}
+			var once sync.Once
+
+			server.AddZone("example.com", &client.DNSRecord{Name: "www", Type: "AAAA", Content: "2001:DB8::1", Expire: 3600})
+
+			server.OnRequest(func(request *transiptest.Request) {
+				if concurrent && request.Method == http.MethodGet && request.Path == "/v6/domains/example.com/dns" {
+					once.Do(func() {
+						server.AddZone("example.com", append(server.Records("example.com"), extra)...)
+					})
+				}
+			})
+
+			_, err := handler.SetRecords(context.Background(), "example.com.", []libdns.Record{
+				libdns.Address{Name: "www", IP: netip.MustParseAddr("2001:db8::2"), TTL: time.Hour},
+			})
+
+			if concurrent && policy == client.ConflictAbort {
+				if false == errors.Is(err, client.ErrZoneModified) {
+					t.Fatalf("expecting zone modified error got %v", err)
+				}
+				continue
+			}
+
+			if err != nil {
+				t.Fatalf("policy %d (concurrent %v): %v", policy, concurrent, err)
+			}
+
+			var records = server.Records("example.com")
+			var expect = 1
+
+			if concurrent {
+				expect = 2
+			}
+
+			if len(records) != expect || records[expect-1].Content != "2001:db8::2" {
+				t.Fatalf("policy %d (concurrent %v): unexpected records %+v", policy, concurrent, records)
+			}
+		}
+	}
+}
+
+func TestProvider_SetRecordsPatch(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	server.AddZone("example.com",
+		&client.DNSRecord{Name: "www", Type: "A", Content: "192.0.2.1", Expire: 3600},
+		&client.DNSRecord{Name: "multi", Type: "A", Content: "192.0.2.1", Expire: 3600},
+		&client.DNSRecord{Name: "multi", Type: "A", Content: "192.0.2.2", Expire: 3600},
+	)
+
+	var methods = func() []string {
+		var methods = make([]string, 0)
+
+		for _, request := range server.Requests() {
+			if request.Path == "/v6/domains/example.com/dns" && request.Method != http.MethodGet {
+				methods = append(methods, request.Method)
+			}
+		}
+
+		return methods
+	}
+
+	for _, x := range []struct {
+		record  libdns.Record
+		methods []string
+	}{
+		// content change can be patched in place
+		{libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.2"), TTL: time.Hour}, []string{http.MethodPatch}},
+		// ttl change requires delete and create
+		{libdns.Address{Name: "www", IP: netip.MustParseAddr("192.0.2.2"), TTL: time.Minute}, []string{http.MethodDelete, http.MethodPost}},
+		// ambiguous entries can not be patched
+		{libdns.Address{Name: "multi", IP: netip.MustParseAddr("192.0.2.3"), TTL: time.Hour}, []string{http.MethodDelete, http.MethodDelete, http.MethodPost}},
+	} {
+		var offset = len(methods())
+
+		if _, err := handler.SetRecords(context.Background(), "example.com.", []libdns.Record{x.record}); err != nil {
+			t.Fatal(err)
+		}
+
+		if curr := methods()[offset:]; false == slices.Equal(curr, x.methods) {
+			t.Fatalf("expecting requests %v got %v", x.methods, curr)
+		}
+	}
+
+	var records = server.Records("example.com")
+
+	if len(records) != 2 || records[0].Content != "192.0.2.2" || records[0].Expire != 60 || records[1].Content != "192.0.2.3" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestProvider_TTLPolicy(t *testing.T) {
+	for _, mode := range []client.ControleMode{client.RecordLevelControl, client.FullZoneControl} {
+		handler, server := newTestProvider(t, mode)
+		handler.TTLPolicy = client.TTLReject
+
+		var record = libdns.TXT{Name: "www", Text: "foo", TTL: 2 * time.Hour}
+
+		if _, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{record}); false == errors.Is(err, client.ErrInvalidRecord) {
+			t.Fatalf("expecting invalid record error got %v", err)
+		}
+
+		var adjusted = make(map[string]time.Duration)
+
+		handler.TTLPolicy = client.TTLRound
+		handler.OnTTLAdjusted = func(zone string, record libdns.RR, ttl time.Duration) {
+			adjusted[record.Name] = ttl
+		}
+
+		if _, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{record, libdns.TXT{Name: "foo", Text: "bar", TTL: 5 * time.Minute}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(adjusted) != 1 || adjusted["www"] != time.Hour {
+			t.Fatalf("expecting only www to be adjusted got %v", adjusted)
+		}
+
+		if records := server.Records("example.com"); len(records) != 2 || records[0].Expire != 3600 || records[1].Expire != 300 {
+			t.Fatalf("unexpected records %+v", records)
+		}
+	}
+}
+
+func TestProvider_GetRecordsTypes(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	server.AddZone("example.com",
+		&client.DNSRecord{Name: "@", Type: "MX", Content: "10 mail.example.com.", Expire: 3600},
+		&client.DNSRecord{Name: "_sip._tcp", Type: "SRV", Content: "10 60 5060 sip.example.com.", Expire: 3600},
+		&client.DNSRecord{Name: "@", Type: "CAA", Content: `0 issue "letsencrypt.org"`, Expire: 3600},
+		&client.DNSRecord{Name: "_25._tcp.mail", Type: "TLSA", Content: "3 1 1 0c72ac70b745ac19998811b131d662c9ac69dbdbe7cb23e5b514b56664c5d3d6", Expire: 3600},
+	)
+
+	records, err := handler.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x, ok := records[0].(libdns.MX); false == ok || x.Preference != 10 || x.Target != "mail.example.com." {
+		t.Fatalf("expecting libdns.MX got %#v", records[0])
+	}
+
+	if x, ok := records[1].(libdns.SRV); false == ok || x.Service != "sip" || x.Weight != 60 || x.Port != 5060 {
+		t.Fatalf("expecting libdns.SRV got %#v", records[1])
+	}
+
+	if x, ok := records[2].(libdns.CAA); false == ok || x.Tag != "issue" || x.Value != "letsencrypt.org" {
+		t.Fatalf("expecting libdns.CAA got %#v", records[2])
+	}
+
+	if x, ok := records[3].(client.TLSA); false == ok || x.Usage != 3 || x.Selector != 1 || x.MatchingType != 1 {
+		t.Fatalf("expecting client.TLSA got %#v", records[3])
+	}
+
+	if entry := client.MarshallDNSRecord(records[2], "example.com."); *entry != *server.Records("example.com")[2] {
+		t.Fatalf("expecting symmetric conversion got %+v", entry)
+	}
+}
+
+func TestProvider_RawRecords(t *testing.T) {
+	for _, mode := range []client.ControleMode{client.RecordLevelControl, client.FullZoneControl} {
+		handler, server := newTestProvider(t, mode)
+
+		server.AddZone("example.com",
+			&client.DNSRecord{Name: "@", Type: "CAA", Content: "0 issue letsencrypt.org", Expire: 3600},
+			&client.DNSRecord{Name: "www", Type: "AAAA", Content: "2001:DB8::1", Expire: 3600},
+			&client.DNSRecord{Name: "foo", Type: "SRV", Content: "10 60 5060 sip.example.com.", Expire: 3600},
+			&client.DNSRecord{Name: "www", Type: "A", Content: "192.0.2.1", Expire: 3600},
+		)
+
+		records, err := handler.GetRecords(context.Background(), "example.com.")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, entry := range server.Records("example.com") {
+			if x := client.MarshallDNSRecord(records[i], "example.com."); *x != *entry {
+				t.Fatalf("expecting content to be preserved %+v got %+v", entry, x)
+			}
+		}
+
+		if _, ok := records[0].(client.RawRecord); false == ok {
+			t.Fatalf("expecting client.RawRecord got %#v", records[0])
+		}
+
+		if _, ok := records[3].(libdns.Address); false == ok {
+			t.Fatalf("expecting libdns.Address got %#v", records[3])
+		}
+
+		if _, err := handler.DeleteRecords(context.Background(), "example.com.", records); err != nil {
+			t.Fatal(err)
+		}
+
+		if records := server.Records("example.com"); len(records) != 0 {
+			t.Fatalf("expecting all records to be deleted got %+v", records)
+		}
+	}
+}
+
+func TestProvider_StoredRecordsNotValidated(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	server.AddZone("example.com",
+		&client.DNSRecord{Name: "sub", Type: "DS", Content: "12345 13 3 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6", Expire: 3600},
+		&client.DNSRecord{Name: "_25._tcp.mail", Type: "TLSA", Content: "3 1 1 0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6", Expire: 3600},
+	)
+
+	records, err := handler.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if x, ok := records[0].(client.RawRecord); false == ok || x.Data != server.Records("example.com")[0].Content {
+		t.Fatalf("expecting client.RawRecord got %#v", records[0])
+	}
+
+	if x, ok := records[1].(client.TLSA); false == ok || x.Certificate != "0C72AC70B745AC19998811B131D662C9AC69DBDBE7CB23E5B514B56664C5D3D6" {
+		t.Fatalf("expecting client.TLSA got %#v", records[1])
+	}
+
+	if _, err := handler.DeleteRecords(context.Background(), "example.com.", records); err != nil {
+		t.Fatal(err)
+	}
+
+	if records := server.Records("example.com"); len(records) != 0 {
+		t.Fatalf("expecting all records to be deleted got %+v", records)
+	}
+}
+
+func TestProvider_InvalidRecords(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	server.AddZone("example.com")
+
+	for _, record := range []libdns.Record{
+		libdns.RR{Name: "_25._tcp.mail", TTL: time.Hour, Type: "TLSA", Data: "3 1 1 0123456789abcdef"},
+		libdns.RR{Name: "_443._tcp", TTL: time.Hour, Type: "TLSA", Data: "4 1 1 0c72ac70b745ac19998811b131d662c9ac69dbdbe7cb23e5b514b56664c5d3d6"},
+		libdns.RR{Name: "host", TTL: time.Hour, Type: "SSHFP", Data: "5 1 123456789abcdef0123456789abcdef012345678"},
+		libdns.RR{Name: "host", TTL: time.Hour, Type: "SSHFP", Data: "4 2 zz"},
+		libdns.RR{Name: "sub", TTL: time.Hour, Type: "DS", Data: "12345 13 2 abcd"},
+		libdns.RR{Name: "@", TTL: time.Hour, Type: "NAPTR", Data: `100 10 "X" "SIP+D2U" "" _sip._udp.example.com.`},
+	} {
+		var requests = len(server.Requests())
+
+		_, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{record})
+
+		if false == errors.Is(err, client.ErrInvalidRecord) {
+			t.Fatalf("expecting invalid record error for %#v got %v", record, err)
+		}
+
+		for _, request := range server.Requests()[requests:] {
+			if request.Method != http.MethodGet && strings.HasSuffix(request.Path, "/dns") {
+				t.Fatalf("expecting no changes to be sent got %s %s", request.Method, request.Path)
+			}
+		}
+	}
+
+	var records = []libdns.Record{
+		client.SSHFP{Name: "host", TTL: time.Hour, Algorithm: 4, Type: 2, Fingerprint: "0c72ac70b745ac19998811b131d662c9ac69dbdbe7cb23e5b514b56664c5d3d6"},
+		client.DS{Name: "sub", TTL: time.Hour, KeyTag: 12345, Algorithm: 13, DigestType: 1, Digest: "123456789abcdef0123456789abcdef012345678"},
+		client.NAPTR{Name: "@", TTL: time.Hour, Order: 100, Preference: 10, Flags: "S", Service: "SIP+D2U", Replacement: "_sip._udp.example.com."},
+		client.ALIAS{Name: "@", TTL: time.Hour, Target: "example.net."},
+	}
+
+	if _, err := handler.AppendRecords(context.Background(), "example.com.", records); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := handler.GetRecords(context.Background(), "example.com.")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, record := range records {
+		if stored[i] != record {
+			t.Fatalf("expecting %#v got %#v", record, stored[i])
+		}
+	}
+}
+
+func TestProvider_EncryptedTokenStorage(t *testing.T) {
+	var root = t.TempDir()
+	var payload = base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, time.Now().Add(time.Hour).Unix())))
+	var jwt = "eyJhbGciOiJSUzUxMiJ9." + payload + ".c2lnbmF0dXJl"
+
+	token, err := client.NewToken(jwt)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage, err := client.NewEncryptedTokenFileStorage(root, []byte("secret"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.Set("key", token); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "key.enc"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte(payload)) {
+		t.Fatal("expecting token to be encrypted")
+	}
+
+	storage, _ = client.NewEncryptedTokenFileStorage(root, []byte("secret"))
+
+	if x, err := storage.Get("key"); err != nil || nil == x || x.String() != jwt {
+		t.Fatalf("expecting stored token got %v (%v)", x, err)
+	}
+
+	storage, _ = client.NewEncryptedTokenFileStorage(root, []byte("other"))
+
+	if x, err := storage.Get("key"); err != nil || nil != x {
+		t.Fatalf("expecting cache miss for other secret got %v (%v)", x, err)
+	}
+
+	data[len(data)-1] ^= 0xff
+
+	if err := os.WriteFile(filepath.Join(root, "key.enc"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	storage, _ = client.NewEncryptedTokenFileStorage(root, []byte("secret"))
+
+	if x, err := storage.Get("key"); err != nil || nil != x {
+		t.Fatalf("expecting cache miss for tampered file got %v (%v)", x, err)
+	}
+
+	// without a secret or private key the tokens can't be encrypted
+	handler, _ := newTestProvider(t, client.RecordLevelControl)
+
+	handler.PrivateKey, handler.TokenStorage, handler.TokenEncryption = filepath.Join(root, "missing.key"), root, true
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); nil == err || false == strings.Contains(err.Error(), "token encryption requires") {
+		t.Fatalf("expecting token encryption error got %v", err)
+	}
+}
+
+func TestProvider_SharedTokenFileStorage(t *testing.T) {
+	var root = t.TempDir()
+	var handlers = make([]*Provider, 5)
+
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	for i := range handlers {
+		handlers[i] = &Provider{
+			AuthLogin:    handler.AuthLogin,
+			PrivateKey:   handler.PrivateKey,
+			BaseUri:      handler.BaseUri,
+			TokenStorage: root,
+		}
+	}
+
+	var group sync.WaitGroup
+
+	for _, x := range handlers {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			if _, err := x.GetRecords(context.Background(), "example.com."); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	group.Wait()
+
+	var auth int
+
+	for _, request := range server.Requests() {
+		if strings.HasSuffix(request.Path, "/auth") {
+			auth++
+		}
+	}
+
+	if auth != 1 {
+		t.Fatalf("expecting 1 authentication got %d", auth)
+	}
+
+	files, err := filepath.Glob(filepath.Join(root, "*.tmp"))
+
+	if err != nil || len(files) > 0 {
+		t.Fatalf("expecting no temporary files got %v (%v)", files, err)
+	}
+}
+
+func TestProvider_TokenSingleFlight(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	var count = func() (auth int) {
+		for _, request := range server.Requests() {
+			if strings.HasSuffix(request.Path, "/auth") {
+				auth++
+			}
+		}
+		return auth
+	}
+
+	var run = func() {
+		var group sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+
+		group.Wait()
+	}
+
+	run()
+
+	if x := count(); x != 1 {
+		t.Fatalf("expecting 1 authentication got %d", x)
+	}
+
+	server.RevokeTokens()
+
+	run()
+
+	if x := count(); x != 2 {
+		t.Fatalf("expecting 2 authentications after revoke got %d", x)
+	}
+}
+
+func TestProvider_TokenSingleFlightCancelled(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	var started, release = make(chan struct{}), make(chan struct{})
+	var once sync.Once
+
+	// hold the first authentication until the leader was cancelled
+	server.OnRequest(func(request *transiptest.Request) {
+		if strings.HasSuffix(request.Path, "/auth") {
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var leader, waiter = make(chan error, 1), make(chan error, 1)
+
+	go func() {
+		_, err := handler.GetRecords(ctx, "example.com.")
+		leader <- err
+	}()
+
+	<-started
+
+	go func() {
+		_, err := handler.GetRecords(context.Background(), "example.com.")
+		waiter <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	if err := <-leader; false == errors.Is(err, context.Canceled) {
+		t.Fatalf("expecting context canceled for the leader got %v", err)
+	}
+
+	close(release)
+
+	if err := <-waiter; err != nil {
+		t.Fatalf("expecting waiter to get a token got %v", err)
+	}
+}
+
+// poll calls fn until it returns true and fails the test when that
+// didn't happen within the timeout.
+func poll(t *testing.T, timeout time.Duration, fn func() bool) {
+	t.Helper()
+
+	var deadline = time.Now().Add(timeout)
+
+	for false == fn() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %s", timeout)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestProvider_TokenRenewal(t *testing.T) {
+
+	var count = func(server *transiptest.Server) (auth int) {
+		for _, request := range server.Requests() {
+			if strings.HasSuffix(request.Path, "/auth") {
+				auth++
+			}
+		}
+		return auth
+	}
+
+	t.Run("ClockSkew", func(t *testing.T) {
+		handler, server := newTestProvider(t, client.RecordLevelControl)
+
+		// tokens would be expired on arrival without skew correction
+		server.SetClockSkew(-2 * time.Hour)
+
+		for i := 0; i < 3; i++ {
+			if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if x := count(server); x != 1 {
+			t.Fatalf("expecting 1 authentication got %d", x)
+		}
+	})
+
+	t.Run("Leeway", func(t *testing.T) {
+		handler, server := newTestProvider(t, client.RecordLevelControl)
+
+		handler.AuthExpirationTime = "4 seconds"
+		handler.TokenRenewalLeeway = time.Hour
+
+		for i := 0; i < 3; i++ {
+			if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// leeway is capped to half the lifetime of the token
+		if x := count(server); x != 1 {
+			t.Fatalf("expecting 1 authentication got %d", x)
+		}
+
+		// the token is renewed halfway so well before it expires
+		poll(t, 3*time.Second, func() bool {
+			if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+				t.Fatal(err)
+			}
+			return count(server) == 2
+		})
+	})
+
+	t.Run("Background", func(t *testing.T) {
+		handler, server := newTestProvider(t, client.RecordLevelControl)
+
+		handler.AuthExpirationTime = "4 seconds"
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		defer cancel()
+
+		handler.RenewTokens(ctx, func(err error) {
+			t.Error(err)
+		})
+
+		poll(t, 3*time.Second, func() bool {
+			return count(server) == 2
+		})
+	})
+}
+
+func TestProvider_ReplayBodyOnUnauthorized(t *testing.T) {
+	for name, mode := range map[string]client.ControleMode{"RecordLevelControl": client.RecordLevelControl, "FullZoneControl": client.FullZoneControl} {
+		t.Run(name, func(t *testing.T) {
+			handler, server := newTestProvider(t, mode)
+
+			server.AddZone("example.com",
+				&client.DNSRecord{Name: "www", Type: "A", Content: "192.0.2.1", Expire: 3600},
+			)
+
+			var once sync.Once
+
+			// revoke after the zone was fetched so the write is denied
+			server.OnRequest(func(request *transiptest.Request) {
+				if request.Method == http.MethodGet && strings.HasSuffix(request.Path, "/dns") {
+					once.Do(server.RevokeTokens)
+				}
+			})
+
+			var records = []libdns.Record{
+				libdns.Address{Name: "www", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.1")},
+				libdns.Address{Name: "mail", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.2")},
+			}
+
+			if _, err := handler.AppendRecords(context.Background(), "example.com.", records[1:]); err != nil {
+				t.Fatal(err)
+			}
+
+			var stored = server.Records("example.com")
+
+			if len(stored) != 2 || stored[1].Name != "mail" || stored[1].Content != "192.0.2.2" {
+				t.Fatalf("expecting the retried request to hold the records got %+v", stored)
+			}
+
+			var unauthorized []*transiptest.Request
+
+			for _, request := range server.Requests() {
+				if request.Status == http.StatusUnauthorized {
+					unauthorized = append(unauthorized, request)
+				}
+			}
+
+			if len(unauthorized) != 1 || unauthorized[0].Method == http.MethodGet {
+				t.Fatalf("expecting 1 unauthorized write got %v", unauthorized)
+			}
+		})
+	}
+}
+
+func TestProvider_Retry(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	handler.RetryBackoff = time.Millisecond
+
+	server.Fail(http.MethodGet, http.StatusBadGateway, "Bad Gateway", 2)
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Fail(http.MethodGet, http.StatusServiceUnavailable, "Service Unavailable", client.DefaultRetryAttempts)
+
+	var response client.ErrorResponse
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); false == errors.As(err, &response) || response.Attempts != client.DefaultRetryAttempts || false == errors.Is(err, client.ErrServer) {
+		t.Fatalf("expecting server error after %d attempts got %v", client.DefaultRetryAttempts, err)
+	}
+
+	// the domain is locked so the request was not processed and can be repeated
+	server.Fail(http.MethodPost, http.StatusConflict, "Another process is already modifying this domain", 1)
+
+	if _, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{libdns.TXT{Name: "a", TTL: time.Hour, Text: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the record could have been created so it should not be repeated
+	server.Fail(http.MethodPost, http.StatusBadGateway, "Bad Gateway", 1)
+
+	if _, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{libdns.TXT{Name: "b", TTL: time.Hour, Text: "b"}}); false == errors.As(err, &response) || response.Attempts != 0 {
+		t.Fatalf("expecting single attempt got %v", err)
+	}
+
+	// errors that are no network errors are not repeated, even when wrapped in an url.Error
+	handler, _ = newTestProvider(t, client.RecordLevelControl)
+
+	key, err := testKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var signer = &countingSigner{Signer: failingSigner{key}}
+
+	handler.PrivateKey, handler.Signer, handler.RetryBackoff = "", signer, time.Millisecond
+
+	var request *url.Error
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); false == errors.As(err, &request) || signer.count.Load() != 1 {
+		t.Fatalf("expecting single attempt got %d (%v)", signer.count.Load(), err)
+	}
+
+	handler, server = newTestProvider(t, client.RecordLevelControl)
+
+	handler.RetryAttempts = -1
+
+	server.Fail(http.MethodGet, http.StatusBadGateway, "Bad Gateway", 1)
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); false == errors.Is(err, client.ErrServer) {
+		t.Fatalf("expecting server error without retries got %v", err)
+	}
+}
+
+func TestProvider_AuthorizeErrors(t *testing.T) {
+	var check = func(t *testing.T, handler *Provider, expected error, message string) {
+		var response client.ErrorResponse
+
+		_, err := handler.GetRecords(context.Background(), "example.com.")
+
+		if false == errors.Is(err, expected) || false == errors.Is(err, client.ErrUnauthorized) && expected != client.ErrNotWhitelisted {
+			t.Fatalf("expecting %v got %v", expected, err)
+		}
+
+		if false == errors.As(err, &response) || false == strings.Contains(response.Message, message) || response.Path != "/v6/auth" {
+			t.Fatalf("expecting response with message %q got %+v", message, response)
+		}
+	}
+
+	t.Run("UnknownLogin", func(t *testing.T) {
+		handler, _ := newTestProvider(t, client.RecordLevelControl)
+		handler.AuthLogin = "other"
+		check(t, handler, client.ErrUnknownLogin, "Unknown login")
+	})
+
+	t.Run("BadSignature", func(t *testing.T) {
+		handler, server := newTestProvider(t, client.RecordLevelControl)
+
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+		handler.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+		check(t, handler, client.ErrBadSignature, "signature")
+
+		for _, request := range server.Requests() {
+			if request.Method == http.MethodGet {
+				t.Fatalf("expecting no requests after failed authentication got %v", request)
+			}
+		}
+	})
+
+	t.Run("NotWhitelisted", func(t *testing.T) {
+		handler, server := newTestProvider(t, client.RecordLevelControl)
+		server.FailAuth(http.StatusForbidden, "Your current IP is not on the whitelist", 1)
+		check(t, handler, client.ErrNotWhitelisted, "whitelist")
+	})
+
+	t.Run("KeyExpired", func(t *testing.T) {
+		handler, server := newTestProvider(t, client.RecordLevelControl)
+		server.FailAuth(http.StatusUnauthorized, "This key has expired", 1)
+		check(t, handler, client.ErrKeyExpired, "expired")
+	})
+
+	t.Run("ClockSkew", func(t *testing.T) {
+		handler, server := newTestProvider(t, client.RecordLevelControl)
+		server.FailAuth(http.StatusUnauthorized, "Request is not valid at this time, check the clock skew", 1)
+		check(t, handler, client.ErrClockSkew, "clock")
+	})
+}
+
+func TestProvider_Permissions(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	permissions, err := handler.CheckPermissions(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *permissions != (client.Permissions{Read: true, Write: true, GlobalKey: true, Whitelisted: true}) {
+		t.Fatalf("unexpected permissions %+v", permissions)
+	}
+
+	handler, server = newTestProvider(t, client.RecordLevelControl)
+	handler.AuthReadOnly = true
+	handler.AuthNotGlobalKey = true
+
+	if permissions, err = handler.CheckPermissions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if *permissions != (client.Permissions{Read: true, Write: false, GlobalKey: false, Whitelisted: true}) {
+		t.Fatalf("unexpected permissions %+v", permissions)
+	}
+
+	var requests = len(server.Requests())
+
+	if _, err := handler.AppendRecords(context.Background(), "example.com.", []libdns.Record{libdns.TXT{Name: "www", TTL: time.Hour, Text: "foo"}}); false == errors.Is(err, client.ErrReadOnly) {
+		t.Fatalf("expecting read-only error got %v", err)
+	}
+
+	if err := handler.SetNameservers(context.Background(), "example.com.", client.DefaultNameservers()); false == errors.Is(err, client.ErrReadOnly) {
+		t.Fatalf("expecting read-only error got %v", err)
+	}
+
+	if x := server.Requests()[requests:]; len(x) > 0 {
+		t.Fatalf("expecting no requests for read-only key got %v", x)
+	}
+
+	handler, server = newTestProvider(t, client.RecordLevelControl)
+	server.FailAuth(http.StatusForbidden, "Your current IP is not on the whitelist", 1)
+
+	if permissions, err = handler.CheckPermissions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if *permissions != (client.Permissions{Whitelisted: false}) {
+		t.Fatalf("unexpected permissions %+v", permissions)
+	}
+}
+
+// countingSigner is a crypto.Signer test double that delegates to a key
+type countingSigner struct {
+	crypto.Signer
+	count atomic.Int32
+}
+
+func (s *countingSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.count.Add(1)
+	return s.Signer.Sign(rand, digest, opts)
+}
+
+// failingSigner is a crypto.Signer test double that refuses to sign
+type failingSigner struct {
+	crypto.Signer
+}
+
+func (failingSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("signer unavailable")
+}
+
+func TestProvider_Signer(t *testing.T) {
+	handler, _ := newTestProvider(t, client.RecordLevelControl)
+
+	key, err := testKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var signer = &countingSigner{Signer: key}
+
+	handler.PrivateKey = ""
+	handler.Signer = signer
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	if signer.count.Load() != 1 {
+		t.Fatalf("expecting the signer to be used once got %d", signer.count.Load())
+	}
+
+	handler, _ = newTestProvider(t, client.RecordLevelControl)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler.Signer = edKey
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); err == nil || false == strings.Contains(err.Error(), "rsa") {
+		t.Fatalf("expecting unsupported key error got %v", err)
+	}
+}
+
+func TestProvider_GetPrivateKey(t *testing.T) {
+	key, err := testKey()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pkcs1 = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	var file = filepath.Join(t.TempDir(), "key.pem")
+
+	if err := os.WriteFile(file, []byte(pkcs1), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{
+		"PKCS1":      pkcs1,
+		"Whitespace": "\n\t  " + pkcs1 + "\n\n",
+		"Base64":     base64.StdEncoding.EncodeToString([]byte(pkcs1)),
+		"File":       file,
+	} {
+		t.Run(name, func(t *testing.T) {
+			x, err := (&Provider{PrivateKey: value}).GetPrivateKey()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if false == x.Equal(key) {
+				t.Fatal("expecting the test key")
+			}
+		})
+	}
+
+	t.Run("EncryptedPKCS8", func(t *testing.T) {
+		var handler = &Provider{PrivateKey: filepath.Join("testdata", "encrypted_key.pem")}
+
+		if _, err := handler.GetPrivateKey(); false == errors.Is(err, client.ErrInvalidPrivateKey) || false == strings.Contains(err.Error(), "passphrase") {
+			t.Fatalf("expecting missing passphrase error got %v", err)
+		}
+
+		handler.PrivateKeyPassphrase = "wrong"
+
+		if _, err := handler.GetPrivateKey(); false == errors.Is(err, client.ErrInvalidPrivateKey) {
+			t.Fatalf("expecting wrong passphrase error got %v", err)
+		}
+
+		handler.PrivateKeyPassphrase = "secret"
+
+		if x, err := handler.GetPrivateKey(); err != nil || x.N.BitLen() != 2048 {
+			t.Fatalf("expecting decrypted key got %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		small, err := rsa.GenerateKey(rand.Reader, 1024)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		der, _ := x509.MarshalPKCS8PrivateKey(small)
+
+		var keyError *client.PrivateKeyError
+
+		_, err = (&Provider{PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))}).GetPrivateKey()
+
+		if false == errors.As(err, &keyError) || keyError.Format != "PKCS #8" || false == strings.Contains(err.Error(), "1024") {
+			t.Fatalf("expecting key size error got %v", err)
+		}
+
+		der, _ = x509.MarshalPKIXPublicKey(&key.PublicKey)
+
+		_, err = (&Provider{PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}).GetPrivateKey()
+
+		if false == errors.As(err, &keyError) || keyError.Format != "public key" {
+			t.Fatalf("expecting public key error got %v", err)
+		}
+	})
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reported []int
+
+	handler.PrivateKeys = []string{
+		string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(other)})),
+		"/does/not/exist.pem",
+		handler.PrivateKey,
+	}
+
+	handler.PrivateKey = ""
+	handler.OnKeyRejected = func(index int, err error) {
+		reported = append(reported, index)
+	}
+
+	var count = func() (auth int) {
+		for _, request := range server.Requests() {
+			if strings.HasSuffix(request.Path, "/auth") {
+				auth++
+			}
+		}
+		return auth
+	}
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	if index, ok := handler.ActiveKey(); false == ok || index != 2 {
+		t.Fatalf("expecting key 2 to be active got %d", index)
+	}
+
+	if rejected := handler.RejectedKeys(); len(rejected) != 2 || false == errors.Is(rejected[0], client.ErrBadSignature) || false == errors.Is(rejected[1], client.ErrInvalidPrivateKey) && false == errors.Is(rejected[1], os.ErrNotExist) {
+		t.Fatalf("unexpected rejected keys %v", rejected)
+	}
+
+	if false == slices.Equal(reported, []int{0, 1}) {
+		t.Fatalf("expecting keys 0 and 1 to be reported got %v", reported)
+	}
+
+	// the accepted key is tried first for new tokens
+	server.RevokeTokens()
+
+	var auth = count()
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	if x := count() - auth; x != 1 {
+		t.Fatalf("expecting 1 authentication got %d", x)
+	}
+
+	handler, _ = newTestProvider(t, client.RecordLevelControl)
+	handler.PrivateKeys = []string{string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(other)}))}
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); false == errors.Is(err, client.ErrBadSignature) {
+		t.Fatalf("expecting bad signature error got %v", err)
+	}
+}
+
+func TestProvider_AuthRequest(t *testing.T) {
+	handler, server := newTestProvider(t, client.RecordLevelControl)
+
+	var nonce int
+
+	handler.AuthLabel = "{hostname} {purpose} {pid}"
+	handler.AuthLabelPurpose = "certificates"
+	handler.NonceGenerator = func() string {
+		nonce++
+		return fmt.Sprintf("nonce-%d", nonce)
+	}
+
+	if err := json.Unmarshal([]byte(`{"expiration_time": "0s"}`), handler); err == nil {
+		t.Fatal("expecting error for a zero expiration time")
+	}
+
+	if err := json.Unmarshal([]byte(`{"expiration_time": "90m"}`), handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := handler.GetRecords(context.Background(), "example.com."); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests = server.AuthRequests()
+	var hostname, _ = os.Hostname()
+
+	if len(requests) != 1 || requests[0].Nonce != "nonce-1" || requests[0].ExpirationTime != "90 minutes" {
+		t.Fatalf("unexpected authentication requests %+v", requests)
+	}
+
+	if prefix := fmt.Sprintf("%s certificates %d - ", hostname, os.Getpid()); false == strings.HasPrefix(requests[0].Label, prefix) || len(requests[0].Label) == len(prefix) {
+		t.Fatalf("expecting label with prefix %q got %q", prefix, requests[0].Label)
+	}
+
+	for duration, expected := range map[time.Duration]client.ExpirationTime{
+		time.Hour:                 "1 hour",
+		36 * time.Hour:            "36 hours",
+		14 * 24 * time.Hour:       "2 weeks",
+		90 * time.Second:          "90 seconds",
+		1500 * time.Millisecond:   "2 seconds",
+		2*time.Hour + time.Minute: "121 minutes",
+		3 * 24 * time.Hour:        "3 days",
+		-1:                        "",
+	} {
+		if x := client.NewExpirationTime(duration); x != expected {
+			t.Fatalf("expecting %q for %s got %q", expected, duration, x)
+		}
+	}
+}
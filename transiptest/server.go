@@ -0,0 +1,234 @@
+// Package transiptest provides an in-memory TransIP API server that can be
+// used to run the provider (and client) tests without a TransIP account.
+//
+//	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+//	server := transiptest.NewServer("user", &key.PublicKey)
+//	defer server.Close()
+//
+//	server.AddZone("example.com")
+//
+//	var provider = &transip.Provider{
+//		AuthLogin:    "user",
+//		PrivateKey:   "...",
+//		BaseUri:      (*transip.ApiBaseUri)(server.BaseUri()),
+//		TokenStorage: "memory",
+//	}
+package transiptest
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/transip/client"
+)
+
+type Server struct {
+	*httptest.Server
+
+	login    string
+	key      *rsa.PublicKey
+	mutex    sync.Mutex
+	zones    map[string]*domain
+	tokens   map[string]*tokenPayload
+	nonces   map[string]struct{}
+	requests []*Request
+	limiter  *rateLimiter
+	hook     func(*Request)
+	skew     time.Duration
+	failures []*failure
+	auth     []*client.AuthRequest
+}
+
+type domain struct {
+	records     []*client.DNSRecord
+	dnssec      []*client.DNSSecEntry
+	nameservers []*client.Nameserver
+}
+
+// Request is a log entry of a request handled by the server
+type Request struct {
+	Method string
+	Path   string
+	Status int
+}
+
+func (r *Request) String() string {
+	return fmt.Sprintf("%s %s %d", r.Method, r.Path, r.Status)
+}
+
+// NewServer starts a new server that accepts authorize requests for the
+// given login which are signed with the private key of the given public
+// key. The caller should call Close when finished, to shut it down.
+func NewServer(login string, key *rsa.PublicKey) *Server {
+
+	var server = &Server{
+		login:  login,
+		key:    key,
+		zones:  make(map[string]*domain),
+		tokens: make(map[string]*tokenPayload),
+		nonces: make(map[string]struct{}),
+	}
+
+	var mux = http.NewServeMux()
+
+	mux.HandleFunc("POST /v6/auth", server.authorize)
+	mux.HandleFunc("GET /v6/api-test", server.authenticated(server.ping, false))
+	mux.HandleFunc("GET /v6/domains", server.authenticated(server.domains, false))
+	mux.HandleFunc("GET /v6/domains/{name}/dns", server.authenticated(server.getDNSEntries, false))
+	mux.HandleFunc("PUT /v6/domains/{name}/dns", server.authenticated(server.putDNSEntries, true))
+	mux.HandleFunc("POST /v6/domains/{name}/dns", server.authenticated(server.postDNSEntry, true))
+	mux.HandleFunc("PATCH /v6/domains/{name}/dns", server.authenticated(server.patchDNSEntry, true))
+	mux.HandleFunc("DELETE /v6/domains/{name}/dns", server.authenticated(server.deleteDNSEntry, true))
+	mux.HandleFunc("GET /v6/domains/{name}/dnssec", server.authenticated(server.getDNSSecEntries, false))
+	mux.HandleFunc("PUT /v6/domains/{name}/dnssec", server.authenticated(server.putDNSSecEntries, true))
+	mux.HandleFunc("GET /v6/domains/{name}/nameservers", server.authenticated(server.getNameservers, false))
+	mux.HandleFunc("PUT /v6/domains/{name}/nameservers", server.authenticated(server.putNameservers, true))
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		writeError(writer, http.StatusNotFound, "Endpoint not found")
+	})
+
+	server.Server = httptest.NewServer(server.log(server.fail(server.rateLimit(mux))))
+
+	return server
+}
+
+// BaseUri returns the uri that can be used as base uri for the client.
+func (s *Server) BaseUri() *url.URL {
+	uri, _ := url.Parse(s.URL + "/v6/")
+	return uri
+}
+
+// AddZone registers a domain with the given records, replacing the
+// records when the domain already exists.
+func (s *Server) AddZone(name string, records ...*client.DNSRecord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var entries = make([]*client.DNSRecord, len(records))
+
+	for i, record := range records {
+		entries[i] = copyRecord(record)
+	}
+
+	if zone, ok := s.zones[strings.TrimSuffix(name, ".")]; ok {
+		zone.records = entries
+	} else {
+		s.zones[strings.TrimSuffix(name, ".")] = &domain{records: entries, nameservers: client.DefaultNameservers()}
+	}
+}
+
+// Records returns a copy of the records for the given domain.
+func (s *Server) Records(name string) []*client.DNSRecord {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var records = make([]*client.DNSRecord, 0)
+
+	if zone, ok := s.zones[strings.TrimSuffix(name, ".")]; ok {
+		for _, record := range zone.records {
+			records = append(records, copyRecord(record))
+		}
+	}
+
+	return records
+}
+
+// Requests returns the log of all handled requests
+func (s *Server) Requests() []*Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]*Request(nil), s.requests...)
+}
+
+func (s *Server) log(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var recorder = &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+
+		handler.ServeHTTP(recorder, request)
+
+		var entry = &Request{Method: request.Method, Path: request.URL.Path, Status: recorder.status}
+
+		s.mutex.Lock()
+		s.requests = append(s.requests, entry)
+		var hook = s.hook
+		s.mutex.Unlock()
+
+		if nil != hook {
+			hook(entry)
+		}
+	})
+}
+
+// OnRequest registers a function that is called after every handled
+// request, which can be used to simulate concurrent changes.
+func (s *Server) OnRequest(fn func(*Request)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.hook = fn
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// domain returns the domain for the name path value of the request, writing
+// a not found response when the domain does not exist.
+func (s *Server) domain(writer http.ResponseWriter, request *http.Request) (*domain, bool) {
+	zone, ok := s.zones[request.PathValue("name")]
+
+	if false == ok {
+		writeError(writer, http.StatusNotFound, "Domain not found")
+	}
+
+	return zone, ok
+}
+
+func (s *Server) domainNames() []string {
+	var names = make([]string, 0, len(s.zones))
+
+	for name := range s.zones {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (s *Server) ping(writer http.ResponseWriter, _ *http.Request) {
+	writeJson(writer, http.StatusOK, map[string]string{"ping": "pong"})
+}
+
+func copyRecord(record *client.DNSRecord) *client.DNSRecord {
+	var x = *record
+	return &x
+}
+
+func writeJson(writer http.ResponseWriter, status int, object any) {
+	writer.Header().Set("content-type", "application/json")
+	writer.WriteHeader(status)
+
+	if nil != object {
+		_ = json.NewEncoder(writer).Encode(object)
+	}
+}
+
+func writeError(writer http.ResponseWriter, status int, message string) {
+	writeJson(writer, status, &client.ErrorResponse{Message: message})
+}
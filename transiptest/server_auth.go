@@ -0,0 +1,231 @@
+package transiptest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/transip/client"
+)
+
+type tokenPayload struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	Id        string `json:"jti"`
+	NotBefore int64  `json:"nbf"`
+	IssuedAt  int64  `json:"iat"`
+	Expires   int64  `json:"exp"`
+	ReadOnly  bool   `json:"ro"`
+	GlobalKey bool   `json:"gk"`
+}
+
+func (s *Server) authorize(writer http.ResponseWriter, request *http.Request) {
+
+	body, err := io.ReadAll(request.Body)
+
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(request.Header.Get("signature"))
+
+	if err != nil || len(signature) == 0 {
+		writeError(writer, http.StatusBadRequest, "Signature not provided or invalid")
+		return
+	}
+
+	var hash = sha512.Sum512(body)
+
+	if err := rsa.VerifyPKCS1v15(s.key, crypto.SHA512, hash[:], signature); err != nil {
+		writeError(writer, http.StatusUnauthorized, "Provided signature is not valid")
+		return
+	}
+
+	var payload *client.AuthRequest
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeError(writer, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if payload.Login != s.login {
+		writeError(writer, http.StatusUnauthorized, "Unknown login")
+		return
+	}
+
+	duration, err := expirationDuration(payload.ExpirationTime)
+
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.nonces[payload.Nonce]; ok || "" == payload.Nonce {
+		writeError(writer, http.StatusUnauthorized, "Nonce has already been used")
+		return
+	}
+
+	s.nonces[payload.Nonce] = struct{}{}
+	s.auth = append(s.auth, payload)
+
+	var now = time.Now().Add(s.skew)
+	var claims = &tokenPayload{
+		Issuer:    "api.transip.nl",
+		Audience:  "api.transip.nl",
+		Id:        random(16),
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		Expires:   now.Add(duration).Unix(),
+		ReadOnly:  payload.ReadOnly,
+		GlobalKey: payload.GlobalKey,
+	}
+
+	token, err := encodeToken(claims)
+
+	if err != nil {
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.tokens[token] = claims
+
+	writeJson(writer, http.StatusCreated, map[string]string{"token": token})
+}
+
+// authenticated wraps the handler with a check for a valid bearer token,
+// write will deny requests for tokens that are read only.
+func (s *Server) authenticated(handler http.HandlerFunc, write bool) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+
+		value, ok := strings.CutPrefix(request.Header.Get("authorization"), "Bearer ")
+
+		if false == ok {
+			writeError(writer, http.StatusUnauthorized, "No authorization header provided")
+			return
+		}
+
+		s.mutex.Lock()
+		claims, ok := s.tokens[value]
+		now := time.Now().Add(s.skew)
+		s.mutex.Unlock()
+
+		if false == ok {
+			writeError(writer, http.StatusUnauthorized, "Your access token is invalid")
+			return
+		}
+
+		if time.Unix(claims.Expires, 0).Before(now) {
+			writeError(writer, http.StatusUnauthorized, "Your access token has expired")
+			return
+		}
+
+		if write && claims.ReadOnly {
+			writeError(writer, http.StatusForbidden, "This is a read-only key")
+			return
+		}
+
+		handler(writer, request)
+	}
+}
+
+func encodeToken(claims *tokenPayload) (string, error) {
+
+	header, err := json.Marshal(map[string]string{"typ": "JWT", "alg": "RS512", "jti": claims.Id})
+
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(header),
+		base64.RawURLEncoding.EncodeToString(payload),
+		base64.RawURLEncoding.EncodeToString([]byte(random(32))),
+	}, "."), nil
+}
+
+func random(size int) string {
+	var buf = make([]byte, size)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// RevokeTokens invalidates all issued tokens, so following requests
+// will be denied with a 401 response.
+func (s *Server) RevokeTokens() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	clear(s.tokens)
+}
+
+// SetClockSkew sets the difference between the clock of the server
+// and the local clock, which is used for issuing and checking tokens.
+func (s *Server) SetClockSkew(skew time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.skew = skew
+}
+
+// AuthRequests returns the payload of the accepted authentication requests
+func (s *Server) AuthRequests() []*client.AuthRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]*client.AuthRequest(nil), s.auth...)
+}
+
+// expirationDuration parses the expiration time (e.g. "1 hour", "120 minutes",
+// "4 weeks") into a time.Duration.
+func expirationDuration(e client.ExpirationTime) (time.Duration, error) {
+	var fields = strings.Fields(string(e))
+
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid expiration time \"%s\"", e)
+	}
+
+	value, err := strconv.Atoi(fields[0])
+
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid expiration time \"%s\"", e)
+	}
+
+	var unit time.Duration
+
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "second":
+		unit = time.Second
+	case "minute":
+		unit = time.Minute
+	case "hour":
+		unit = time.Hour
+	case "day":
+		unit = 24 * time.Hour
+	case "week":
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid expiration time unit \"%s\"", fields[1])
+	}
+
+	return time.Duration(value) * unit, nil
+}
@@ -0,0 +1,194 @@
+package transiptest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/libdns/transip/client"
+)
+
+func (s *Server) getDNSEntries(writer http.ResponseWriter, request *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zone, ok := s.domain(writer, request)
+
+	if false == ok {
+		return
+	}
+
+	writeJson(writer, http.StatusOK, &client.DNSEntries{Entries: zone.records})
+}
+
+func (s *Server) putDNSEntries(writer http.ResponseWriter, request *http.Request) {
+	var data *client.DNSEntries
+
+	if err := json.NewDecoder(request.Body).Decode(&data); err != nil || nil == data || nil == data.Entries {
+		writeError(writer, http.StatusBadRequest, "Invalid request body, expected 'dnsEntries'")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zone, ok := s.domain(writer, request)
+
+	if false == ok {
+		return
+	}
+
+	var entries = make([]*client.DNSRecord, 0, len(data.Entries))
+
+	for _, entry := range data.Entries {
+
+		if err := validate(entry); err != nil {
+			writeError(writer, http.StatusNotAcceptable, err.Error())
+			return
+		}
+
+		if index(entries, entry, true) >= 0 {
+			writeError(writer, http.StatusNotAcceptable, "This DNS entry already exists")
+			return
+		}
+
+		entries = append(entries, copyRecord(entry))
+	}
+
+	zone.records = entries
+
+	writeJson(writer, http.StatusNoContent, nil)
+}
+
+func (s *Server) postDNSEntry(writer http.ResponseWriter, request *http.Request) {
+	s.mutateEntry(writer, request, func(entries []*client.DNSRecord, entry *client.DNSRecord) ([]*client.DNSRecord, int, error) {
+
+		if err := validate(entry); err != nil {
+			return nil, http.StatusNotAcceptable, err
+		}
+
+		if index(entries, entry, true) >= 0 {
+			return nil, http.StatusNotAcceptable, errors.New("This DNS entry already exists")
+		}
+
+		return append(entries, copyRecord(entry)), http.StatusCreated, nil
+	})
+}
+
+// patchDNSEntry will update the content of a single entry, the entry is
+// identified by the name, expire and type.
+func (s *Server) patchDNSEntry(writer http.ResponseWriter, request *http.Request) {
+	s.mutateEntry(writer, request, func(entries []*client.DNSRecord, entry *client.DNSRecord) ([]*client.DNSRecord, int, error) {
+
+		if err := validate(entry); err != nil {
+			return nil, http.StatusNotAcceptable, err
+		}
+
+		var match *client.DNSRecord
+
+		for _, record := range entries {
+			if strings.EqualFold(record.Name, entry.Name) && record.Type == entry.Type && record.Expire == entry.Expire {
+
+				if nil != match {
+					return nil, http.StatusNotAcceptable, errors.New("Multiple DNS entries match the given entry")
+				}
+
+				match = record
+			}
+		}
+
+		if nil == match {
+			return nil, http.StatusNotFound, errors.New("DNS entry not found")
+		}
+
+		match.Content = entry.Content
+
+		return entries, http.StatusNoContent, nil
+	})
+}
+
+func (s *Server) deleteDNSEntry(writer http.ResponseWriter, request *http.Request) {
+	s.mutateEntry(writer, request, func(entries []*client.DNSRecord, entry *client.DNSRecord) ([]*client.DNSRecord, int, error) {
+
+		var idx = index(entries, entry, false)
+
+		if idx < 0 {
+			return nil, http.StatusNotFound, errors.New("DNS entry not found")
+		}
+
+		return append(entries[:idx], entries[idx+1:]...), http.StatusNoContent, nil
+	})
+}
+
+func (s *Server) mutateEntry(writer http.ResponseWriter, request *http.Request, fn func([]*client.DNSRecord, *client.DNSRecord) ([]*client.DNSRecord, int, error)) {
+	var data *client.DNSEntry
+
+	if err := json.NewDecoder(request.Body).Decode(&data); err != nil || nil == data || nil == data.Entry {
+		writeError(writer, http.StatusBadRequest, "Invalid request body, expected 'dnsEntry'")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zone, ok := s.domain(writer, request)
+
+	if false == ok {
+		return
+	}
+
+	entries, status, err := fn(zone.records, data.Entry)
+
+	if err != nil {
+		writeError(writer, status, err.Error())
+		return
+	}
+
+	zone.records = entries
+
+	writeJson(writer, status, nil)
+}
+
+// index returns the position of the entry in the given list or -1 when
+// not found, when ignoreExpire is set the expire is not compared.
+func index(entries []*client.DNSRecord, entry *client.DNSRecord, ignoreExpire bool) int {
+	for i, record := range entries {
+		if strings.EqualFold(record.Name, entry.Name) && record.Type == entry.Type && record.Content == entry.Content && (ignoreExpire || record.Expire == entry.Expire) {
+			return i
+		}
+	}
+	return -1
+}
+
+func validate(entry *client.DNSRecord) error {
+
+	if false == client.IsSupportedType(entry.Type) {
+		return fmt.Errorf("Unsupported DNS entry type '%s'", entry.Type)
+	}
+
+	if "" == entry.Name {
+		return errors.New("DNS entry name is required")
+	}
+
+	if entry.Expire <= 0 {
+		return errors.New("DNS entry expire should be a positive number")
+	}
+
+	if "" == entry.Content {
+		return errors.New("DNS entry content is required")
+	}
+
+	switch entry.Type {
+	case "A", "AAAA":
+		addr, err := netip.ParseAddr(entry.Content)
+
+		if err != nil || addr.Is4() != (entry.Type == "A") {
+			return fmt.Errorf("Invalid content '%s' for DNS entry of type %s", entry.Content, entry.Type)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package transiptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/libdns/transip/client"
+)
+
+// SetDNSSEC replaces the DNSSEC entries of the given domain
+func (s *Server) SetDNSSEC(name string, entries ...*client.DNSSecEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if zone, ok := s.zones[strings.TrimSuffix(name, ".")]; ok {
+		zone.dnssec = entries
+	}
+}
+
+func (s *Server) getDNSSecEntries(writer http.ResponseWriter, request *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zone, ok := s.domain(writer, request)
+
+	if false == ok {
+		return
+	}
+
+	var entries = zone.dnssec
+
+	if nil == entries {
+		entries = make([]*client.DNSSecEntry, 0)
+	}
+
+	writeJson(writer, http.StatusOK, &client.DNSSecEntries{Entries: entries})
+}
+
+func (s *Server) putDNSSecEntries(writer http.ResponseWriter, request *http.Request) {
+	var data *client.DNSSecEntries
+
+	if err := json.NewDecoder(request.Body).Decode(&data); err != nil || nil == data || nil == data.Entries {
+		writeError(writer, http.StatusBadRequest, "Invalid request body, expected 'dnsSecEntries'")
+		return
+	}
+
+	for _, entry := range data.Entries {
+		if err := entry.Validate(); err != nil {
+			writeError(writer, http.StatusNotAcceptable, err.Error())
+			return
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zone, ok := s.domain(writer, request)
+
+	if false == ok {
+		return
+	}
+
+	zone.dnssec = data.Entries
+
+	writeJson(writer, http.StatusNoContent, nil)
+}
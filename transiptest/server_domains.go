@@ -0,0 +1,53 @@
+package transiptest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/libdns/transip/client"
+)
+
+// domains returns the registered domains, when the pageSize query parameter
+// is given the result is paginated and the _links contain the next/previous
+// pages like the TransIP api does.
+func (s *Server) domains(writer http.ResponseWriter, request *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var names = s.domainNames()
+	var links = client.Links{{Rel: "self", Link: s.URL + request.URL.RequestURI()}}
+
+	if size, err := strconv.Atoi(request.URL.Query().Get("pageSize")); err == nil && size > 0 {
+		page, err := strconv.Atoi(request.URL.Query().Get("page"))
+
+		if err != nil || page <= 0 {
+			page = 1
+		}
+
+		var last = (len(names) + size - 1) / size
+		var link = func(rel string, page int) *client.Link {
+			return &client.Link{Rel: rel, Link: fmt.Sprintf("%s/v6/domains?page=%d&pageSize=%d", s.URL, page, size)}
+		}
+
+		links = append(links, link("first", 1), link("last", max(last, 1)))
+
+		if page > 1 {
+			links = append(links, link("previous", page-1))
+		}
+
+		if page < last {
+			links = append(links, link("next", page+1))
+		}
+
+		names = names[min((page-1)*size, len(names)):min(page*size, len(names))]
+	}
+
+	var domains = make([]*client.Domain, 0, len(names))
+
+	for _, name := range names {
+		domains = append(domains, &client.Domain{Name: name})
+	}
+
+	writeJson(writer, http.StatusOK, map[string]any{"domains": domains, "_links": links})
+}
@@ -0,0 +1,58 @@
+package transiptest
+
+import (
+	"net/http"
+	"strings"
+)
+
+type failure struct {
+	method  string
+	status  int
+	message string
+	count   int
+	auth    bool
+}
+
+// Fail makes the next count requests with the given method (authentication
+// excluded) fail with the status and message, which can be used to test
+// the handling of transient errors.
+func (s *Server) Fail(method string, status int, message string, count int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures = append(s.failures, &failure{method: method, status: status, message: message, count: count})
+}
+
+// FailAuth makes the next count authentication requests fail with the
+// status and message.
+func (s *Server) FailAuth(status int, message string, count int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures = append(s.failures, &failure{method: http.MethodPost, status: status, message: message, count: count, auth: true})
+}
+
+func (s *Server) fail(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var auth = strings.HasSuffix(request.URL.Path, "/auth")
+
+		s.mutex.Lock()
+
+		for i, failure := range s.failures {
+			if failure.method == request.Method && failure.auth == auth {
+
+				if failure.count--; failure.count <= 0 {
+					s.failures = append(s.failures[:i], s.failures[i+1:]...)
+				}
+
+				s.mutex.Unlock()
+				writeError(writer, failure.status, failure.message)
+				return
+			}
+		}
+
+		s.mutex.Unlock()
+
+		handler.ServeHTTP(writer, request)
+	})
+}
@@ -0,0 +1,50 @@
+package transiptest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/libdns/transip/client"
+)
+
+func (s *Server) getNameservers(writer http.ResponseWriter, request *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zone, ok := s.domain(writer, request)
+
+	if false == ok {
+		return
+	}
+
+	writeJson(writer, http.StatusOK, &client.Nameservers{Nameservers: zone.nameservers})
+}
+
+func (s *Server) putNameservers(writer http.ResponseWriter, request *http.Request) {
+	var data *client.Nameservers
+
+	if err := json.NewDecoder(request.Body).Decode(&data); err != nil || nil == data || len(data.Nameservers) == 0 {
+		writeError(writer, http.StatusBadRequest, "Invalid request body, expected 'nameservers'")
+		return
+	}
+
+	for _, nameserver := range data.Nameservers {
+		if err := nameserver.Validate(request.PathValue("name")); err != nil {
+			writeError(writer, http.StatusNotAcceptable, err.Error())
+			return
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	zone, ok := s.domain(writer, request)
+
+	if false == ok {
+		return
+	}
+
+	zone.nameservers = data.Nameservers
+
+	writeJson(writer, http.StatusNoContent, nil)
+}
@@ -0,0 +1,70 @@
+package transiptest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	start  time.Time
+	count  int
+}
+
+// SetRateLimit enables a request quota of limit requests per window, which
+// is reported through the X-Rate-Limit-* headers. Requests exceeding the
+// quota get a 429 response. A limit of zero disables the quota.
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if limit <= 0 {
+		s.limiter = nil
+		return
+	}
+
+	s.limiter = &rateLimiter{limit: limit, window: window}
+}
+
+func (s *Server) rateLimit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		s.mutex.Lock()
+
+		var limiter = s.limiter
+
+		if nil == limiter {
+			s.mutex.Unlock()
+			handler.ServeHTTP(writer, request)
+			return
+		}
+
+		var now = time.Now()
+
+		if now.Sub(limiter.start) >= limiter.window {
+			limiter.start = now
+			limiter.count = 0
+		}
+
+		limiter.count++
+
+		var remaining = max(limiter.limit-limiter.count, 0)
+		var exceeded = limiter.count > limiter.limit
+		var reset = limiter.start.Add(limiter.window)
+
+		s.mutex.Unlock()
+
+		// round up so clients never retry before the actual reset
+		writer.Header().Set("X-Rate-Limit-Limit", strconv.Itoa(limiter.limit))
+		writer.Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(remaining))
+		writer.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(reset.Add(time.Second-1).Unix(), 10))
+
+		if exceeded {
+			writeError(writer, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+
+		handler.ServeHTTP(writer, request)
+	})
+}
@@ -0,0 +1,227 @@
+package zonefile
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+type token struct {
+	// value is the unescaped value of the token
+	value string
+	// raw is the token as it was written in the file
+	raw    string
+	quoted bool
+}
+
+// entry is a logical line of the zone file, which can span multiple lines
+// when parentheses are used.
+type entry struct {
+	line   int
+	indent bool
+	tokens []*token
+}
+
+type lexer struct {
+	reader *bufio.Reader
+	line   int
+}
+
+func (l *lexer) read() (rune, error) {
+	r, _, err := l.reader.ReadRune()
+
+	if err == nil && r == '\n' {
+		l.line++
+	}
+
+	return r, err
+}
+
+func (l *lexer) unread(r rune) {
+	_ = l.reader.UnreadRune()
+
+	if r == '\n' {
+		l.line--
+	}
+}
+
+// next returns the next non-empty entry or io.EOF when done
+func (l *lexer) next() (*entry, error) {
+	for {
+		var curr = &entry{line: l.line}
+		var depth = 0
+		var start = true
+
+	scan:
+		for {
+			r, err := l.read()
+
+			if err == io.EOF {
+				if depth > 0 {
+					return nil, &ParseError{Line: curr.line, Err: errors.New("unbalanced parentheses")}
+				}
+
+				if len(curr.tokens) > 0 {
+					return curr, nil
+				}
+
+				return nil, io.EOF
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case r == '\n':
+				if depth == 0 {
+					break scan
+				}
+			case r == ' ' || r == '\t' || r == '\r':
+				if start {
+					curr.indent = true
+				}
+			case r == ';':
+				for r != '\n' {
+					if r, err = l.read(); err != nil {
+						break
+					}
+				}
+
+				if err == nil {
+					l.unread(r)
+				}
+			case r == '(':
+				depth++
+			case r == ')':
+				if depth--; depth < 0 {
+					return nil, &ParseError{Line: l.line, Err: errors.New("unbalanced parentheses")}
+				}
+			case r == '"':
+				x, err := l.quoted()
+
+				if err != nil {
+					return nil, err
+				}
+
+				curr.tokens = append(curr.tokens, x)
+			default:
+				l.unread(r)
+
+				x, err := l.word()
+
+				if err != nil {
+					return nil, err
+				}
+
+				curr.tokens = append(curr.tokens, x)
+			}
+
+			start = false
+		}
+
+		if len(curr.tokens) > 0 {
+			return curr, nil
+		}
+	}
+}
+
+func (l *lexer) quoted() (*token, error) {
+	var value, raw strings.Builder
+	var line = l.line
+
+	raw.WriteByte('"')
+
+	for {
+		r, err := l.read()
+
+		if err != nil || r == '\n' {
+			return nil, &ParseError{Line: line, Err: errors.New("unterminated quoted string")}
+		}
+
+		raw.WriteRune(r)
+
+		switch r {
+		case '"':
+			return &token{value: value.String(), raw: raw.String(), quoted: true}, nil
+		case '\\':
+			if err := l.escape(&value, &raw); err != nil {
+				return nil, err
+			}
+		default:
+			value.WriteRune(r)
+		}
+	}
+}
+
+func (l *lexer) word() (*token, error) {
+	var value, raw strings.Builder
+
+	for {
+		r, err := l.read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.ContainsRune(" \t\r\n;()\"", r) {
+			l.unread(r)
+			break
+		}
+
+		raw.WriteRune(r)
+
+		if r == '\\' {
+			if err := l.escape(&value, &raw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		value.WriteRune(r)
+	}
+
+	return &token{value: value.String(), raw: raw.String()}, nil
+}
+
+// escape handles the \X and \DDD escape sequences
+func (l *lexer) escape(value, raw *strings.Builder) error {
+	r, err := l.read()
+
+	if err != nil || r == '\n' {
+		return &ParseError{Line: l.line, Err: errors.New("invalid escape sequence")}
+	}
+
+	raw.WriteRune(r)
+
+	if r < '0' || r > '9' {
+		value.WriteRune(r)
+		return nil
+	}
+
+	var code = int(r - '0')
+
+	for i := 0; i < 2; i++ {
+		r, err := l.read()
+
+		if err != nil || r < '0' || r > '9' {
+			return &ParseError{Line: l.line, Err: errors.New("invalid \\DDD escape sequence")}
+		}
+
+		raw.WriteRune(r)
+		code = code*10 + int(r-'0')
+	}
+
+	if code > 255 {
+		return &ParseError{Line: l.line, Err: errors.New("invalid \\DDD escape sequence")}
+	}
+
+	value.WriteByte(byte(code))
+
+	return nil
+}
@@ -0,0 +1,330 @@
+package zonefile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/libdns/transip/client"
+)
+
+var ErrUnsupportedType = errors.New("record type not supported by TransIP")
+
+// ParseError is returned when the zone file could not be parsed and
+// holds the line number of the offending entry.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+type parser struct {
+	zone   string
+	origin string
+	ttl    *time.Duration
+	last   *time.Duration
+	owner  string
+}
+
+// Parse reads an RFC 1035 master file for the given zone and returns the
+// records with names relative to the zone, so they can be used with the
+// Provider (SetRecords, AppendRecords etc.).
+//
+// The zone is used as initial $ORIGIN, SOA records are skipped because
+// they are managed by TransIP and all other record types that can't be
+// stored at TransIP are rejected with an ErrUnsupportedType error.
+func Parse(reader io.Reader, zone string) ([]libdns.Record, error) {
+	var lexer = &lexer{reader: bufio.NewReader(reader), line: 1}
+	var records = make([]libdns.Record, 0)
+	var parser = &parser{
+		zone:   fqdn(zone),
+		origin: fqdn(zone),
+	}
+
+	for {
+		entry, err := lexer.next()
+
+		if err == io.EOF {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(entry.tokens[0].value, "$") && false == entry.indent {
+			if err := parser.directive(entry); err != nil {
+				return nil, &ParseError{Line: entry.line, Err: err}
+			}
+			continue
+		}
+
+		record, err := parser.record(entry)
+
+		if err != nil {
+			return nil, &ParseError{Line: entry.line, Err: err}
+		}
+
+		if nil != record {
+			records = append(records, record)
+		}
+	}
+}
+
+func (p *parser) directive(entry *entry) error {
+
+	if len(entry.tokens) < 2 {
+		return fmt.Errorf("missing value for %s", entry.tokens[0].value)
+	}
+
+	switch strings.ToUpper(entry.tokens[0].value) {
+	case "$ORIGIN":
+		p.origin = p.absolute(entry.tokens[1].value)
+	case "$TTL":
+		ttl, err := parseTTL(entry.tokens[1].value)
+
+		if err != nil {
+			return err
+		}
+
+		p.ttl = &ttl
+	default:
+		return fmt.Errorf("unsupported directive %s", entry.tokens[0].value)
+	}
+
+	return nil
+}
+
+func (p *parser) record(entry *entry) (libdns.Record, error) {
+	var tokens = entry.tokens
+
+	if false == entry.indent {
+		p.owner = p.absolute(tokens[0].value)
+		tokens = tokens[1:]
+	}
+
+	if "" == p.owner {
+		return nil, errors.New("missing owner name")
+	}
+
+	var ttl *time.Duration
+
+	// the ttl and class are optional and can be in any order
+optional:
+	for i := 0; i < 2 && len(tokens) > 0; i++ {
+
+		if x, err := parseTTL(tokens[0].value); err == nil && nil == ttl {
+			ttl = &x
+			tokens = tokens[1:]
+			continue
+		}
+
+		switch strings.ToUpper(tokens[0].value) {
+		case "IN":
+			tokens = tokens[1:]
+		case "CH", "HS", "CS", "ANY":
+			return nil, fmt.Errorf("unsupported class %s", tokens[0].value)
+		default:
+			break optional
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("missing record type")
+	}
+
+	var kind = strings.ToUpper(tokens[0].value)
+	var data = tokens[1:]
+
+	if nil != ttl {
+		p.last = ttl
+	}
+
+	if "SOA" == kind {
+		return nil, nil
+	}
+
+	if false == client.IsSupportedType(kind) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, kind)
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing data for %s record", kind)
+	}
+
+	if nil == ttl {
+		if ttl = p.ttl; nil == ttl {
+			if ttl = p.last; nil == ttl {
+				return nil, errors.New("missing ttl and no $TTL specified")
+			}
+		}
+	}
+
+	name, err := p.relative(p.owner)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rr = libdns.RR{
+		Name: name,
+		TTL:  *ttl,
+		Type: kind,
+		Data: join(kind, p.targets(kind, data)),
+	}
+
+	record, err := rr.Parse()
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s record: %w", kind, err)
+	}
+
+	return record, nil
+}
+
+// absolute returns the fully qualified name for given name
+func (p *parser) absolute(name string) string {
+
+	if "@" == name {
+		return p.origin
+	}
+
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+
+	return name + "." + p.origin
+}
+
+// relative returns the name relative to the zone
+func (p *parser) relative(name string) (string, error) {
+
+	if strings.EqualFold(name, p.zone) {
+		return "@", nil
+	}
+
+	if len(name) > len(p.zone) && strings.EqualFold(name[len(name)-len(p.zone)-1:], "."+p.zone) {
+		return name[:len(name)-len(p.zone)-1], nil
+	}
+
+	return "", fmt.Errorf("name %s is outside of zone %s", name, p.zone)
+}
+
+// targetIndex holds the position of the domain name in the data of the
+// record types that refer to another name
+var targetIndex = map[string]int{"CNAME": 0, "NS": 0, "ALIAS": 0, "MX": 1, "SRV": 3, "NAPTR": 5}
+
+// targets resolves a relative domain name in the data against the current
+// $ORIGIN and makes it relative to the zone again, because TransIP resolves
+// relative names against the zone.
+func (p *parser) targets(kind string, tokens []*token) []*token {
+	var index, ok = targetIndex[kind]
+
+	if false == ok || index >= len(tokens) || strings.HasSuffix(tokens[index].value, ".") {
+		return tokens
+	}
+
+	var name = p.absolute(tokens[index].value)
+
+	if x, err := p.relative(name); err == nil {
+		name = x
+	}
+
+	var list = slices.Clone(tokens)
+
+	list[index] = &token{value: name, raw: name}
+
+	return list
+}
+
+// join returns the record data, where the character strings of TXT records
+// are unquoted and concatenated and all other data is kept as written.
+func join(kind string, tokens []*token) string {
+	var parts = make([]string, len(tokens))
+
+	for i, token := range tokens {
+		if "TXT" == kind {
+			parts[i] = token.value
+		} else {
+			parts[i] = token.raw
+		}
+	}
+
+	if "TXT" == kind {
+		return strings.Join(parts, "")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// parseTTL parses the ttl in seconds or in the BIND format like 1h30m
+func parseTTL(x string) (time.Duration, error) {
+
+	if "" == x {
+		return 0, errors.New("empty ttl")
+	}
+
+	if v, err := strconv.ParseUint(x, 10, 32); err == nil {
+		return time.Duration(v) * time.Second, nil
+	}
+
+	var ttl time.Duration
+	var value uint64
+	var digits bool
+
+	for _, r := range strings.ToLower(x) {
+
+		if r >= '0' && r <= '9' {
+			value = value*10 + uint64(r-'0')
+			digits = true
+			continue
+		}
+
+		if false == digits {
+			return 0, fmt.Errorf("invalid ttl %s", x)
+		}
+
+		switch {
+		case r == 's':
+			ttl += time.Duration(value) * time.Second
+		case r == 'm':
+			ttl += time.Duration(value) * time.Minute
+		case r == 'h':
+			ttl += time.Duration(value) * time.Hour
+		case r == 'd':
+			ttl += time.Duration(value) * 24 * time.Hour
+		case r == 'w':
+			ttl += time.Duration(value) * 7 * 24 * time.Hour
+		default:
+			return 0, fmt.Errorf("invalid ttl %s", x)
+		}
+
+		value, digits = 0, false
+	}
+
+	if digits {
+		return 0, fmt.Errorf("invalid ttl %s", x)
+	}
+
+	return ttl, nil
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
@@ -0,0 +1,105 @@
+package zonefile
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Write writes the records as an RFC 1035 master file for the given zone.
+//
+// The file starts with an $ORIGIN and a $TTL (the most used ttl) so names
+// are written relative to the zone and the ttl is only written for records
+// that differ from the default.
+func Write(writer io.Writer, zone string, records []libdns.Record) error {
+	var ttl = defaultTTL(records)
+	var buf = tabwriter.NewWriter(writer, 0, 4, 1, ' ', 0)
+
+	if _, err := fmt.Fprintf(buf, "$ORIGIN %s\n$TTL %d\n", fqdn(zone), int64(ttl.Seconds())); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		var rr = record.RR()
+		var name = rr.Name
+		var expire string
+
+		if "" == name {
+			name = "@"
+		}
+
+		if rr.TTL != ttl {
+			expire = fmt.Sprintf("%d", int64(rr.TTL.Seconds()))
+		}
+
+		var data = rr.Data
+
+		if "TXT" == rr.Type {
+			data = quote(rr.Data)
+		}
+
+		if _, err := fmt.Fprintf(buf, "%s\t%s\tIN\t%s\t%s\n", name, expire, rr.Type, data); err != nil {
+			return err
+		}
+	}
+
+	return buf.Flush()
+}
+
+// defaultTTL returns the most used ttl, on a tie the lowest wins
+func defaultTTL(records []libdns.Record) time.Duration {
+	var count = make(map[time.Duration]int)
+	var ttl time.Duration
+
+	for _, record := range records {
+		count[record.RR().TTL]++
+	}
+
+	for x, c := range count {
+		if c > count[ttl] || (c == count[ttl] && x < ttl) {
+			ttl = x
+		}
+	}
+
+	return ttl
+}
+
+// quote returns the text as one or more quoted character strings (of
+// at most 255 bytes) with quotes, backslashes and non-printable bytes
+// escaped.
+func quote(text string) string {
+	var parts = make([]string, 0, len(text)/255+1)
+
+	for {
+		var size = min(len(text), 255)
+		var buf strings.Builder
+
+		buf.WriteByte('"')
+
+		for i := 0; i < size; i++ {
+			switch c := text[i]; {
+			case c == '"' || c == '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case c < 0x20 || c >= 0x7f:
+				_, _ = fmt.Fprintf(&buf, "\\%03d", c)
+			default:
+				buf.WriteByte(c)
+			}
+		}
+
+		buf.WriteByte('"')
+
+		parts = append(parts, buf.String())
+
+		if text = text[size:]; "" == text {
+			break
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
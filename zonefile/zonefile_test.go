@@ -0,0 +1,128 @@
+package zonefile
+
+import (
+	"bytes"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+const zone = `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns0.transip.net. hostmaster.example.com. (
+			2025010101 ; serial
+			3h 1h 1w 1h )
+@		IN	A	192.0.2.1
+		300	IN	AAAA	2001:db8::1
+www	IN	CNAME	@
+@	IN	MX	10 mail.example.com.
+_sip._tcp	IN	SRV	10 60 5060 sip.example.com.
+@	IN	CAA	0 issue "letsencrypt.org"
+txt	IN	TXT	"hello \"world\"" "; not a comment"
+$ORIGIN sub.example.com.
+foo	IN	TXT	"caf\195\169"
+www	IN	CNAME	host
+@	IN	MX	10 mail
+ext	IN	CNAME	host.example.net.
+`
+
+func TestParse(t *testing.T) {
+	records, err := Parse(strings.NewReader(zone), "example.com")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []libdns.RR{
+		{Name: "@", TTL: time.Hour, Type: "A", Data: "192.0.2.1"},
+		{Name: "@", TTL: 300 * time.Second, Type: "AAAA", Data: "2001:db8::1"},
+		{Name: "www", TTL: time.Hour, Type: "CNAME", Data: "@"},
+		{Name: "@", TTL: time.Hour, Type: "MX", Data: "10 mail.example.com."},
+		{Name: "_sip._tcp", TTL: time.Hour, Type: "SRV", Data: "10 60 5060 sip.example.com."},
+		{Name: "@", TTL: time.Hour, Type: "CAA", Data: `0 issue "letsencrypt.org"`},
+		{Name: "txt", TTL: time.Hour, Type: "TXT", Data: `hello "world"; not a comment`},
+		{Name: "foo.sub", TTL: time.Hour, Type: "TXT", Data: "café"},
+		{Name: "www.sub", TTL: time.Hour, Type: "CNAME", Data: "host.sub"},
+		{Name: "sub", TTL: time.Hour, Type: "MX", Data: "10 mail.sub"},
+		{Name: "ext.sub", TTL: time.Hour, Type: "CNAME", Data: "host.example.net."},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("expecting %d records got %d", len(expected), len(records))
+	}
+
+	for i, record := range records {
+		if record.RR() != expected[i] {
+			t.Fatalf("expecting %#v got %#v", expected[i], record.RR())
+		}
+	}
+
+	if _, ok := records[4].(libdns.SRV); false == ok {
+		t.Fatalf("expecting libdns.SRV got %T", records[4])
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for input, line := range map[string]int{
+		"$TTL 3600\n\n@ IN HINFO \"cpu\" \"os\"\n":                3,
+		"$TTL 3600\n; comment\nwww.example.net. IN A 192.0.2.1\n": 3,
+		"@ IN A 192.0.2.1\n":                                      1,
+		"$TTL 3600\n@ IN TXT \"unterminated\n":                    2,
+		"$TTL 3600\n@ IN MX (\n10\n":                              2,
+		"$TTL 3600\n$INCLUDE other.zone\n":                        2,
+	} {
+		_, err := Parse(strings.NewReader(input), "example.com.")
+
+		var parseError *ParseError
+
+		if false == errors.As(err, &parseError) {
+			t.Fatalf("expecting parse error for %q got %v", input, err)
+		}
+
+		if parseError.Line != line {
+			t.Fatalf("expecting error on line %d got %d (%s)", line, parseError.Line, err)
+		}
+	}
+
+	if _, err := Parse(strings.NewReader("$TTL 60\n@ IN HINFO a b\n"), "example.com."); false == errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expecting unsupported type error got %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var records = []libdns.Record{
+		libdns.Address{Name: "@", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.1")},
+		libdns.TXT{Name: "txt", TTL: 5 * time.Minute, Text: `quotes " backslashes \ del: ` + "\x7f" + strings.Repeat("x", 300)},
+		libdns.MX{Name: "@", TTL: time.Hour, Preference: 10, Target: "mail.example.com."},
+	}
+
+	var buf = new(bytes.Buffer)
+
+	if err := Write(buf, "example.com.", records); err != nil {
+		t.Fatal(err)
+	}
+
+	if false == strings.HasPrefix(buf.String(), "$ORIGIN example.com.\n$TTL 3600\n") {
+		t.Fatalf("unexpected header:\n%s", buf.String())
+	}
+
+	parsed, err := Parse(buf, "example.com.")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parsed) != len(records) {
+		t.Fatalf("expecting %d records got %d", len(records), len(parsed))
+	}
+
+	for i, record := range parsed {
+		if record.RR() != records[i].RR() {
+			t.Fatalf("expecting %#v got %#v", records[i].RR(), record.RR())
+		}
+	}
+}